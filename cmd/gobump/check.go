@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+	"github.com/chainguard-dev/gobump/pkg/update"
+	"github.com/spf13/cobra"
+)
+
+type checkCLIFlags struct {
+	packages string
+	bumpFile string
+	modroot  string
+	replaces string
+	output   string
+}
+
+var checkFlags checkCLIFlags
+
+// checkCmd verifies that go.mod already satisfies a list of bumps, without editing it.
+var checkCmd = &cobra.Command{
+	Use:          "check",
+	Short:        "Check that go.mod already satisfies the requested versions",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if checkFlags.output != outputText && checkFlags.output != outputJSON {
+			return fmt.Errorf("invalid output format %q. Use %q or %q", checkFlags.output, outputText, outputJSON)
+		}
+
+		pkgVersions, _, err := parsePackages(checkFlags.packages, checkFlags.replaces, checkFlags.bumpFile)
+		if err != nil {
+			return err
+		}
+
+		unsatisfied, err := update.Check(pkgVersions, checkFlags.modroot)
+		if err != nil {
+			return fmt.Errorf("failed to run check. Error: %w", err)
+		}
+		if err := printUnsatisfied(cmd.OutOrStdout(), unsatisfied); err != nil {
+			return err
+		}
+		if len(unsatisfied) > 0 {
+			return fmt.Errorf("%d of %d packages are not satisfied by go.mod", len(unsatisfied), len(pkgVersions))
+		}
+		return nil
+	},
+}
+
+// printUnsatisfied writes the packages that failed the check in the requested output format.
+func printUnsatisfied(w io.Writer, unsatisfied []types.PackageResult) error {
+	if checkFlags.output == outputJSON {
+		if unsatisfied == nil {
+			unsatisfied = []types.PackageResult{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(unsatisfied)
+	}
+	for _, u := range unsatisfied {
+		if _, err := fmt.Fprintln(w, u.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	flagSet := checkCmd.Flags()
+	flagSet.StringVar(&checkFlags.packages, "packages", "", "A space-separated list of packages to check")
+	flagSet.StringVar(&checkFlags.bumpFile, "bump-file", "", "Filename containing the list of packages to check")
+	flagSet.StringVar(&checkFlags.modroot, "modroot", "", "path to the go.mod root")
+	flagSet.StringVar(&checkFlags.replaces, "replaces", "", "A space-separated list of replaces to check")
+	flagSet.StringVar(&checkFlags.output, "output", outputText, "Output format of the unsatisfied packages, one of 'text' or 'json'")
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import "github.com/chainguard-dev/gobump/pkg/types"
+
+// Exit codes of gobump when --detailed-exit-codes is set. Without it gobump
+// exits with ExitChanged on success and ExitFailed on failure.
+const (
+	// ExitChanged means the update succeeded and go.mod changed (or would change, on a dry run).
+	ExitChanged = 0
+	// ExitFailed means the update failed.
+	ExitFailed = 1
+	// ExitNoop means the update succeeded but go.mod did not change.
+	ExitNoop = 2
+	// ExitSkipped means the update succeeded but at least one requested package, or go or
+	// toolchain line, was skipped, e.g. because go.mod already has a newer version.
+	ExitSkipped = 3
+)
+
+// ExitError reports an outcome that must make gobump exit with Code.
+type ExitError struct {
+	Code    int
+	Message string
+}
+
+func (e *ExitError) Error() string {
+	return e.Message
+}
+
+// exitCodeFor maps the results of a successful update, one per module, to its detailed exit code.
+func exitCodeFor(results ...*types.Result) int {
+	changed := false
+	for _, result := range results {
+		for _, p := range result.Packages {
+			if p.Skipped {
+				return ExitSkipped
+			}
+		}
+		for _, d := range result.Directives {
+			if d.Skipped {
+				return ExitSkipped
+			}
+		}
+		changed = changed || result.Changed
+	}
+	if !changed {
+		return ExitNoop
+	}
+	return ExitChanged
+}
+
+// exitErrorFor returns the *ExitError for the results of a successful update, or nil if it is ExitChanged.
+func exitErrorFor(results ...*types.Result) error {
+	switch exitCodeFor(results...) {
+	case ExitSkipped:
+		return &ExitError{Code: ExitSkipped, Message: "some requested packages were skipped"}
+	case ExitNoop:
+		return &ExitError{Code: ExitNoop, Message: "go.mod was not changed"}
+	}
+	return nil
+}
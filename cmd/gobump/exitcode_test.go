@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	testCases := []struct {
+		name   string
+		result *types.Result
+		want   int
+	}{
+		{
+			name: "changed",
+			result: &types.Result{
+				Changed:  true,
+				Packages: []types.PackageResult{{Name: "github.com/google/uuid"}},
+			},
+			want: ExitChanged,
+		},
+		{
+			name: "nothing changed",
+			result: &types.Result{
+				Packages: []types.PackageResult{{Name: "github.com/google/uuid"}},
+			},
+			want: ExitNoop,
+		},
+		{
+			name: "some packages skipped",
+			result: &types.Result{
+				Changed: true,
+				Packages: []types.PackageResult{
+					{Name: "github.com/google/uuid"},
+					{Name: "k8s.io/api", Skipped: true, SkipReason: types.SkipReasonDowngrade},
+				},
+			},
+			want: ExitSkipped,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := exitCodeFor(tc.result); got != tc.want {
+				t.Errorf("exitCodeFor() = %d, want %d", got, tc.want)
+			}
+			err := exitErrorFor(tc.result)
+			if tc.want == ExitChanged {
+				if err != nil {
+					t.Errorf("exitErrorFor() = %v, want nil", err)
+				}
+				return
+			}
+			if exitErr, ok := err.(*ExitError); !ok || exitErr.Code != tc.want {
+				t.Errorf("exitErrorFor() = %v, want an *ExitError with code %d", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestExitCodeForModules(t *testing.T) {
+	unchanged := &types.Result{Packages: []types.PackageResult{}}
+	changed := &types.Result{Changed: true, Packages: []types.PackageResult{{Name: "github.com/google/uuid"}}}
+	skipped := &types.Result{Packages: []types.PackageResult{{Name: "github.com/google/uuid", Skipped: true}}}
+
+	if got := exitCodeFor(unchanged, changed); got != ExitChanged {
+		t.Errorf("exitCodeFor(unchanged, changed) = %d, want %d", got, ExitChanged)
+	}
+	if got := exitCodeFor(unchanged, unchanged); got != ExitNoop {
+		t.Errorf("exitCodeFor(unchanged, unchanged) = %d, want %d", got, ExitNoop)
+	}
+	if got := exitCodeFor(changed, skipped); got != ExitSkipped {
+		t.Errorf("exitCodeFor(changed, skipped) = %d, want %d", got, ExitSkipped)
+	}
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+	"github.com/chainguard-dev/gobump/pkg/update"
+	"github.com/spf13/cobra"
+)
+
+type pruneCLIFlags struct {
+	modroot         string
+	tidy            bool
+	goVersion       string
+	goVersionSource []string
+	goBinary        string
+	goEnv           []string
+	showDiff        bool
+	dryRun          bool
+	transactional   bool
+	output          string
+}
+
+var pruneFlags pruneCLIFlags
+
+// pruneCmd drops the replaces pinning a module to a version go.mod already requires.
+var pruneCmd = &cobra.Command{
+	Use:          "prune-replaces",
+	Short:        "Drop the replaces pinning a module to a version that go.mod already requires",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if pruneFlags.output != outputText && pruneFlags.output != outputJSON {
+			return fmt.Errorf("invalid output format %q. Use %q or %q", pruneFlags.output, outputText, outputJSON)
+		}
+		runner, err := goRunner(pruneFlags.goBinary, pruneFlags.goEnv)
+		if err != nil {
+			return err
+		}
+
+		result, err := update.PruneReplaces(cmd.Context(), &types.Config{
+			Modroot:          pruneFlags.modroot,
+			Tidy:             pruneFlags.tidy,
+			GoVersion:        pruneFlags.goVersion,
+			GoVersionSources: goVersionSources(pruneFlags.goVersionSource),
+			ShowDiff:         showDiffFor(pruneFlags.showDiff, pruneFlags.output),
+			DryRun:           pruneFlags.dryRun,
+			Transactional:    pruneFlags.transactional,
+			Runner:           runner,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to prune the replaces. Error: %w", err)
+		}
+		return printPruned(cmd.OutOrStdout(), result, pruneFlags.output)
+	},
+}
+
+// printPruned writes the pruned replaces in the requested output format.
+func printPruned(w io.Writer, result *types.PruneResult, output string) error {
+	if output == outputJSON {
+		if result.Pruned == nil {
+			result.Pruned = []types.PrunedReplace{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+	verb := "pruned"
+	if result.DryRun {
+		verb = "would prune"
+	}
+	for _, p := range result.Pruned {
+		old := p.Path
+		if p.OldVersion != "" {
+			old += " " + p.OldVersion
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s => %s %s (requires %s)\n", verb, old, p.Path, p.Version, p.Required); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	flagSet := pruneCmd.Flags()
+	flagSet.StringVar(&pruneFlags.modroot, "modroot", "", "path to the go.mod root")
+	flagSet.BoolVar(&pruneFlags.tidy, "tidy", true, "Run 'go mod tidy' command after dropping the replaces")
+	flagSet.StringVar(&pruneFlags.goVersion, "go-version", "", "set the go-version for go-mod-tidy")
+	flagSet.StringSliceVar(&pruneFlags.goVersionSource, "go-version-source", nil, "Comma-separated sources of the go version for go-mod-tidy, tried in order: flag, env, gomod, gowork, toolchain. Defaults to flag,env")
+	flagSet.StringVar(&pruneFlags.goBinary, "go-binary", "", "The go command to run, defaults to go from PATH")
+	flagSet.StringArrayVar(&pruneFlags.goEnv, "go-env", nil, "KEY=VALUE for the go commands, one of GOTOOLCHAIN, GOFLAGS, GOPROXY, GONOSUMDB, GOPRIVATE, GOMODCACHE or GOWORK. Can be repeated. When set, none of these variables is inherited from the environment")
+	flagSet.BoolVar(&pruneFlags.showDiff, "show-diff", false, "Show the difference between the original and 'go.mod' files")
+	flagSet.BoolVar(&pruneFlags.dryRun, "dry-run", false, "Report the stale replaces without modifying go.mod")
+	flagSet.BoolVar(&pruneFlags.transactional, "transactional", false, "Restore go.mod, go.sum, go.work, go.work.sum and vendor/modules.txt if pruning fails")
+	flagSet.StringVar(&pruneFlags.output, "output", outputText, "Output format of the pruned replaces, one of 'text' or 'json'")
+}
@@ -2,12 +2,19 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log"
 	"strings"
+	"time"
 
+	"github.com/chainguard-dev/gobump/pkg/run"
 	"github.com/chainguard-dev/gobump/pkg/types"
 	"github.com/chainguard-dev/gobump/pkg/update"
 	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
 	"sigs.k8s.io/release-utils/version"
 )
 
@@ -22,81 +29,345 @@ type rootCLIFlags struct {
 	showDiff        bool
 	tidyCompat      string
 	work            bool
+	dryRun          bool
+	output          string
+	transactional   bool
+	editBackend     string
+	timeout         time.Duration
+	batchGet        bool
+	annotate        bool
+	detailedExit    bool
+	govulncheckFile string
+	recursive       bool
+	workspace       bool
+	goDirective     string
+	toolchain       string
+	forceDirectives bool
+	maxGoVersion    string
+	goVersionSource []string
+	goBinary        string
+	goEnv           []string
 }
 
 var rootFlags rootCLIFlags
 
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
 // rootCmd represents the base command when called without any subcommands.
 var rootCmd = &cobra.Command{
 	Use:          "gobump",
 	Short:        "gobump cli",
 	Args:         cobra.NoArgs,
 	SilenceUsage: true,
-	RunE: func(_ *cobra.Command, _ []string) error {
-		if rootFlags.packages == "" && rootFlags.replaces == "" && rootFlags.bumpFile == "" {
-			return fmt.Errorf("no packages or replaces provided. Use --packages or --replaces or --bump-file")
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if rootFlags.output != outputText && rootFlags.output != outputJSON {
+			return fmt.Errorf("invalid output format %q. Use %q or %q", rootFlags.output, outputText, outputJSON)
 		}
 
-		if rootFlags.packages != "" && rootFlags.bumpFile != "" {
-			return fmt.Errorf("both --packages and --bump-file flags are provided. Use only one")
+		if backend := types.EditBackend(rootFlags.editBackend); backend != types.EditBackendGo && backend != types.EditBackendModfile {
+			return fmt.Errorf("invalid edit backend %q. Use %q or %q", rootFlags.editBackend, types.EditBackendGo, types.EditBackendModfile)
 		}
 
-		if rootFlags.replaces != "" && rootFlags.bumpFile != "" {
-			return fmt.Errorf("both --replaces and --bump-file flags are provided. Use only one")
-		}
+		directives := types.Directives{Go: rootFlags.goDirective, Toolchain: rootFlags.toolchain, Force: rootFlags.forceDirectives}
+		onlyDirectives := rootFlags.packages == "" && rootFlags.replaces == "" && rootFlags.bumpFile == "" && rootFlags.govulncheckFile == "" &&
+			(directives.Go != "" || directives.Toolchain != "")
 
-		pkgVersions := map[string]*types.Package{}
-		if rootFlags.bumpFile != "" {
-			var err error
-			pkgVersions, err = types.ParseFile(rootFlags.bumpFile)
-			if err != nil {
-				return fmt.Errorf("failed to parse bump file %q: %v", rootFlags.bumpFile, err)
+		var pkgVersions map[string]*types.Package
+		var err error
+		switch {
+		case onlyDirectives:
+			pkgVersions = map[string]*types.Package{}
+		case rootFlags.govulncheckFile != "":
+			if rootFlags.packages != "" || rootFlags.replaces != "" || rootFlags.bumpFile != "" {
+				return fmt.Errorf("--govulncheck-file can't be combined with --packages, --replaces or --bump-file. Use only one")
 			}
-		} else {
-			packages := strings.Fields(rootFlags.packages)
-			for i, pkg := range packages {
-				parts := strings.Split(pkg, "@")
-				if len(parts) != 2 {
-					return fmt.Errorf("invalid package format. Each package should be in the format <package@version>. Usage: gobump --packages=\"<package1@version> <package2@version> ...\"")
-				}
-				pkgVersions[parts[0]] = &types.Package{
-					Name:    parts[0],
-					Version: parts[1],
-					Index:   i,
-				}
+			if pkgVersions, err = govulncheckPackages(rootFlags.govulncheckFile); err != nil {
+				return err
 			}
-
-			if len(rootFlags.replaces) != 0 {
-				replaces := strings.Fields(rootFlags.replaces)
-				for i, replace := range replaces {
-					parts := strings.Split(replace, "=")
-					if len(parts) != 2 {
-						return fmt.Errorf("invalid replace format. Each replace should be in the format <oldpackage=newpackage@version>. Usage: gobump -replaces=\"<oldpackage=newpackage@version> ...\"")
-					}
-					// extract the new package name and version
-					rep := strings.Split(strings.TrimPrefix(replace, fmt.Sprintf("%s=", parts[0])), "@")
-					if len(rep) != 2 {
-						return fmt.Errorf("invalid replace format. Each replace should be in the format <oldpackage=newpackage@version>. Usage: gobump -replaces=\"<oldpackage=newpackage@version> ...\"")
-					}
-					// Merge/Add the packages to replace reusing the initial list of packages
-					pkgVersions[rep[0]] = &types.Package{
-						OldName: parts[0],
-						Name:    rep[0],
-						Version: rep[1],
-						Replace: true,
-						Index:   i,
-					}
+			if len(pkgVersions) == 0 && directives.Go == "" && directives.Toolchain == "" {
+				log.Println("No reachable vulnerability with a fixed version was found")
+				if rootFlags.detailedExit {
+					return &ExitError{Code: ExitNoop, Message: "go.mod was not changed"}
 				}
+				return nil
+			}
+		default:
+			var fileDirectives types.Directives
+			if pkgVersions, fileDirectives, err = parsePackages(rootFlags.packages, rootFlags.replaces, rootFlags.bumpFile); err != nil {
+				return err
+			}
+			// The flags win over the bump file.
+			if directives.Go == "" {
+				directives.Go = fileDirectives.Go
 			}
+			if directives.Toolchain == "" {
+				directives.Toolchain = fileDirectives.Toolchain
+			}
+			directives.Force = directives.Force || fileDirectives.Force
+		}
+		if err := directives.Validate(); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		if rootFlags.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, rootFlags.timeout)
+			defer cancel()
+		}
+
+		runner, err := goRunner(rootFlags.goBinary, rootFlags.goEnv)
+		if err != nil {
+			return err
+		}
+		cfg := &types.Config{
+			Modroot:          rootFlags.modroot,
+			Tidy:             rootFlags.tidy,
+			GoVersion:        rootFlags.goVersion,
+			GoVersionSources: goVersionSources(rootFlags.goVersionSource),
+			ShowDiff:         showDiffFor(rootFlags.showDiff, rootFlags.output),
+			TidyCompat:       rootFlags.tidyCompat,
+			TidySkipInitial:  rootFlags.skipInitialTidy,
+			ForceWork:        rootFlags.work,
+			DryRun:           rootFlags.dryRun,
+			Transactional:    rootFlags.transactional,
+			EditBackend:      types.EditBackend(rootFlags.editBackend),
+			BatchGet:         rootFlags.batchGet,
+			Annotate:         rootFlags.annotate,
+			Directives:       directives,
+			MaxGoVersion:     rootFlags.maxGoVersion,
+			Runner:           runner,
+		}
+		if rootFlags.recursive && rootFlags.workspace {
+			return fmt.Errorf("both --recursive and --workspace flags are provided. Use only one")
+		}
+		if rootFlags.recursive {
+			return runRecursive(ctx, cmd.OutOrStdout(), pkgVersions, cfg)
+		}
+		if rootFlags.workspace {
+			return runWorkspace(ctx, cmd.OutOrStdout(), pkgVersions, cfg)
 		}
 
-		if _, err := update.DoUpdate(pkgVersions, &types.Config{Modroot: rootFlags.modroot, Tidy: rootFlags.tidy, GoVersion: rootFlags.goVersion, ShowDiff: rootFlags.showDiff, TidyCompat: rootFlags.tidyCompat, TidySkipInitial: rootFlags.skipInitialTidy, ForceWork: rootFlags.work}); err != nil {
-			return fmt.Errorf("failed to run update. Error: %v", err)
+		result, err := update.DoUpdateContext(ctx, pkgVersions, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to run update. Error: %w", err)
+		}
+		if err := printResult(cmd.OutOrStdout(), result, rootFlags.output, rootFlags.showDiff); err != nil {
+			return err
+		}
+		if rootFlags.detailedExit {
+			return exitErrorFor(result)
 		}
 		return nil
 	},
 }
 
+// runRecursive applies the bumps to every module found under the modroot and prints one result per module.
+func runRecursive(ctx context.Context, w io.Writer, pkgVersions map[string]*types.Package, cfg *types.Config) error {
+	root := cfg.Modroot
+	if root == "" {
+		root = "."
+	}
+	modroots, err := update.FindModules(root)
+	if err != nil {
+		return err
+	}
+	if len(modroots) == 0 {
+		return fmt.Errorf("no go.mod found under %s", root)
+	}
+	cfg.Modroots = modroots
+
+	results, err := update.DoUpdateModules(ctx, pkgVersions, cfg)
+	if err != nil {
+		// The modules updated before the failure are only rolled back with --transactional.
+		for _, result := range results {
+			if result.Changed {
+				log.Printf("Warning: module %s was updated before the failure and is left as is\n", result.Modroot)
+			}
+		}
+		if len(results) > 0 {
+			if perr := printModules(w, results, rootFlags.output, rootFlags.showDiff); perr != nil {
+				return perr
+			}
+		}
+		return fmt.Errorf("failed to run update. Error: %w", err)
+	}
+	if err := printModules(w, results, rootFlags.output, rootFlags.showDiff); err != nil {
+		return err
+	}
+	if rootFlags.detailedExit {
+		return exitErrorFor(results...)
+	}
+	return nil
+}
+
+// printModules writes the results of an update of several modules in the requested output format.
+func printModules(w io.Writer, results []*types.Result, output string, showDiff bool) error {
+	if output == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+	for _, result := range results {
+		if err := printResult(w, result, output, showDiff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runWorkspace applies the bumps to every module of the workspace of the modroot and prints one result per module.
+func runWorkspace(ctx context.Context, w io.Writer, pkgVersions map[string]*types.Package, cfg *types.Config) error {
+	result, err := update.DoUpdateWorkspace(ctx, pkgVersions, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to run update. Error: %w", err)
+	}
+	if rootFlags.output == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		for _, m := range result.Modules {
+			if err := printResult(w, m, rootFlags.output, rootFlags.showDiff); err != nil {
+				return err
+			}
+		}
+	}
+	if rootFlags.detailedExit {
+		// The go.work replaces count as one more module.
+		replaces := &types.Result{Packages: result.Replaces}
+		for _, r := range result.Replaces {
+			replaces.Changed = replaces.Changed || r.Before != r.After
+		}
+		return exitErrorFor(append(result.Modules, replaces)...)
+	}
+	return nil
+}
+
+// parsePackages builds the list of packages to bump from either the --packages and --replaces
+// flags or the --bump-file flag, along with the go and toolchain directives of the bump file.
+func parsePackages(packages, replaces, bumpFile string) (map[string]*types.Package, types.Directives, error) {
+	if packages == "" && replaces == "" && bumpFile == "" {
+		return nil, types.Directives{}, fmt.Errorf("no packages or replaces provided. Use --packages or --replaces or --bump-file")
+	}
+
+	if packages != "" && bumpFile != "" {
+		return nil, types.Directives{}, fmt.Errorf("both --packages and --bump-file flags are provided. Use only one")
+	}
+
+	if replaces != "" && bumpFile != "" {
+		return nil, types.Directives{}, fmt.Errorf("both --replaces and --bump-file flags are provided. Use only one")
+	}
+
+	if bumpFile != "" {
+		pkgVersions, directives, err := types.ParseFileWithDirectives(bumpFile)
+		if err != nil {
+			return nil, types.Directives{}, fmt.Errorf("failed to parse bump file %q: %v", bumpFile, err)
+		}
+		return pkgVersions, directives, nil
+	}
+
+	pkgVersions := map[string]*types.Package{}
+	for i, pkg := range strings.Fields(packages) {
+		parts := strings.Split(pkg, "@")
+		if len(parts) != 2 {
+			return nil, types.Directives{}, fmt.Errorf("invalid package format. Each package should be in the format <package@version>. Usage: gobump --packages=\"<package1@version> <package2@version> ...\"")
+		}
+		if types.IsVersionQuery(parts[1]) {
+			if _, err := types.ParseVersionQuery(parts[1]); err != nil {
+				return nil, types.Directives{}, err
+			}
+		}
+		pkgVersions[parts[0]] = &types.Package{
+			Name:    parts[0],
+			Version: parts[1],
+			Index:   i,
+		}
+	}
+
+	if len(replaces) != 0 {
+		for i, replace := range strings.Fields(replaces) {
+			parts := strings.Split(replace, "=")
+			if len(parts) != 2 {
+				return nil, types.Directives{}, fmt.Errorf("invalid replace format. Each replace should be in the format <oldpackage=newpackage@version>. Usage: gobump -replaces=\"<oldpackage=newpackage@version> ...\"")
+			}
+			// extract the new package name and version
+			rep := strings.Split(strings.TrimPrefix(replace, fmt.Sprintf("%s=", parts[0])), "@")
+			if len(rep) != 2 {
+				return nil, types.Directives{}, fmt.Errorf("invalid replace format. Each replace should be in the format <oldpackage=newpackage@version>. Usage: gobump -replaces=\"<oldpackage=newpackage@version> ...\"")
+			}
+			// The old package may target the replace of one of its versions, <oldpackage@version=...>.
+			oldName, oldVersion, _ := strings.Cut(parts[0], "@")
+			if parts[0] != oldName && !semver.IsValid(oldVersion) {
+				return nil, types.Directives{}, fmt.Errorf("invalid replace format. The version of the old package %q is not a semantic version", parts[0])
+			}
+			// Merge/Add the packages to replace reusing the initial list of packages
+			pkgVersions[rep[0]] = &types.Package{
+				OldName:    oldName,
+				OldVersion: oldVersion,
+				Name:       rep[0],
+				Version:    rep[1],
+				Replace:    true,
+				Index:      i,
+			}
+		}
+	}
+	return pkgVersions, types.Directives{}, nil
+}
+
+// printResult writes the result of an update in the requested output format.
+// showDiff tells whether the update already printed the diff.
+func printResult(w io.Writer, result *types.Result, output string, showDiff bool) error {
+	if output == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+	// A dry run is only useful if we show what would have changed.
+	if result.DryRun && !showDiff && result.Diff != "" {
+		if _, err := fmt.Fprintln(w, result.Diff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// showDiffFor tells whether the update prints the diff for the --show-diff flag. The JSON report
+// already holds it, printing it to stdout as well would break the JSON.
+func showDiffFor(showDiff bool, output string) bool {
+	return showDiff && output != outputJSON
+}
+
+// goRunner returns the runner of the go commands for the --go-binary and --go-env flags, nil for the default one.
+func goRunner(goBinary string, goEnv []string) (run.Runner, error) {
+	if goBinary == "" && len(goEnv) == 0 {
+		return nil, nil
+	}
+	runner := &run.ExecRunner{GoBinary: goBinary}
+	if len(goEnv) > 0 {
+		env, err := run.ParseGoEnv(goEnv)
+		if err != nil {
+			return nil, err
+		}
+		runner.GoEnv = env
+	}
+	return runner, nil
+}
+
+// goVersionSources converts the --go-version-source values, the update rejects the unknown ones.
+func goVersionSources(values []string) []types.GoVersionSource {
+	sources := make([]types.GoVersionSource, 0, len(values))
+	for _, v := range values {
+		sources = append(sources, types.GoVersionSource(v))
+	}
+	return sources
+}
+
 // RootCmd returns the root cobra command for gobump.
 func RootCmd() *cobra.Command {
 	return rootCmd
@@ -106,16 +377,36 @@ func init() {
 	rootCmd.AddCommand(version.WithFont("starwars"))
 
 	rootCmd.DisableAutoGenTag = true
+	// main reports the errors, with the exit code they map to.
+	rootCmd.SilenceErrors = true
 
 	flagSet := rootCmd.Flags()
 	flagSet.StringVar(&rootFlags.packages, "packages", "", "A space-separated list of packages to update")
 	flagSet.StringVar(&rootFlags.bumpFile, "bump-file", "", "Filename containing the list of packages to update / replace")
 	flagSet.StringVar(&rootFlags.modroot, "modroot", "", "path to the go.mod root")
+	flagSet.BoolVar(&rootFlags.workspace, "workspace", false, "Update every module used by the go.work of --modroot, bumping the go.work replaces, then run 'go work sync'")
+	flagSet.BoolVar(&rootFlags.recursive, "recursive", false, "Update every module found under --modroot that depends on the requested packages")
 	flagSet.StringVar(&rootFlags.replaces, "replaces", "", "A space-separated list of packages to replace")
+	flagSet.StringVar(&rootFlags.govulncheckFile, "govulncheck-file", "", "Output of 'govulncheck -json', the modules with reachable findings are bumped to their fixed version")
 	flagSet.BoolVar(&rootFlags.tidy, "tidy", false, "Run 'go mod tidy' command")
 	flagSet.BoolVar(&rootFlags.skipInitialTidy, "skip-initial-tidy", false, "Skip running 'go mod tidy' command before updating the go.mod file")
 	flagSet.BoolVar(&rootFlags.showDiff, "show-diff", false, "Show the difference between the original and 'go.mod' files")
+	flagSet.StringVar(&rootFlags.goDirective, "go-directive", "", "Raise the go directive of go.mod to this version (e.g. 1.22.5), and the one of go.work to match")
+	flagSet.StringVar(&rootFlags.toolchain, "toolchain", "", "Raise the toolchain line of go.mod to this toolchain (e.g. go1.22.6), and the one of go.work to match")
+	flagSet.BoolVar(&rootFlags.forceDirectives, "force-directives", false, "Allow --go-directive and --toolchain to lower the go and toolchain lines")
+	flagSet.StringVar(&rootFlags.maxGoVersion, "max-go-version", "", "Fail if the update raises the go or toolchain line of go.mod above this go version (e.g. 1.22), naming the bumped packages requiring it")
+	flagSet.StringVar(&rootFlags.goBinary, "go-binary", "", "The go command to run, defaults to go from PATH")
+	flagSet.StringArrayVar(&rootFlags.goEnv, "go-env", nil, "KEY=VALUE for the go commands, one of GOTOOLCHAIN, GOFLAGS, GOPROXY, GONOSUMDB, GOPRIVATE, GOMODCACHE or GOWORK. Can be repeated. When set, none of these variables is inherited from the environment")
 	flagSet.StringVar(&rootFlags.goVersion, "go-version", "", "set the go-version for go-mod-tidy")
+	flagSet.StringSliceVar(&rootFlags.goVersionSource, "go-version-source", nil, "Comma-separated sources of the go version for go-mod-tidy, tried in order: flag (--go-version), env ('go env GOVERSION'), gomod (go line of go.mod), gowork (go line of go.work), toolchain (toolchain line of go.mod). Defaults to flag,env")
 	flagSet.StringVar(&rootFlags.tidyCompat, "compat", "", "set the go version for which the tidied go.mod and go.sum files should be compatible")
 	flagSet.BoolVar(&rootFlags.work, "work", false, "Use 'go work vendor' instead of 'go mod vendor'")
+	flagSet.BoolVar(&rootFlags.transactional, "transactional", false, "Restore go.mod, go.sum, go.work, go.work.sum and vendor/modules.txt if the update fails")
+	flagSet.StringVar(&rootFlags.editBackend, "edit-backend", string(types.EditBackendGo), "How to edit go.mod: 'go' runs 'go mod edit' for every change, 'modfile' applies all changes in memory and writes go.mod once")
+	flagSet.BoolVar(&rootFlags.batchGet, "batch-get", false, "Bump all the requires with a single 'go get', falling back to one 'go get' per package if it fails")
+	flagSet.BoolVar(&rootFlags.annotate, "annotate", false, "Write the reason of every bumped package, from --bump-file or the advisories of --govulncheck-file, as a '// gobump: <reason>' comment on its require or replace line of go.mod")
+	flagSet.DurationVar(&rootFlags.timeout, "timeout", 0, "Abort the update and restore the module if it takes longer than this duration (e.g. 5m), 0 means no limit")
+	flagSet.StringVar(&rootFlags.output, "output", outputText, "Output format of the update report, one of 'text' or 'json'")
+	flagSet.BoolVar(&rootFlags.detailedExit, "detailed-exit-codes", false, "Exit with 0 when go.mod changed, 1 on failure, 2 when nothing changed and 3 when requested packages were skipped")
+	flagSet.BoolVar(&rootFlags.dryRun, "dry-run", false, "Run the update against a scratch copy of the modroot and show what would change without modifying it")
 }
@@ -1,8 +1,15 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
+	"github.com/chainguard-dev/gobump/pkg/types"
 )
 
 func TestRootCmdWorkFlag(t *testing.T) {
@@ -40,3 +47,222 @@ func TestRootCLIFlagsStructure(t *testing.T) {
 		t.Error("work field not properly set in rootCLIFlags")
 	}
 }
+
+func TestPrintResultJSON(t *testing.T) {
+	result := &types.Result{
+		Modroot: "testdata",
+		Packages: []types.PackageResult{{
+			Name:             "github.com/google/uuid",
+			Kind:             types.KindRequire,
+			RequestedVersion: "v1.0.0",
+			Before:           "v1.3.1",
+			After:            "v1.3.1",
+			Skipped:          true,
+			SkipReason:       types.SkipReasonDowngrade,
+		}},
+	}
+	var buf bytes.Buffer
+	if err := printResult(&buf, result, outputJSON, false); err != nil {
+		t.Fatalf("printResult() = %v", err)
+	}
+
+	var got types.Result
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if len(got.Packages) != 1 || !got.Packages[0].Skipped || got.Packages[0].SkipReason != types.SkipReasonDowngrade {
+		t.Errorf("unexpected packages in report: %+v", got.Packages)
+	}
+}
+
+func TestShowDiffFor(t *testing.T) {
+	for _, tc := range []struct {
+		showDiff bool
+		output   string
+		want     bool
+	}{
+		{showDiff: true, output: outputText, want: true},
+		{showDiff: false, output: outputText, want: false},
+		// The diff is in the JSON report, printing it would break the JSON.
+		{showDiff: true, output: outputJSON, want: false},
+	} {
+		if got := showDiffFor(tc.showDiff, tc.output); got != tc.want {
+			t.Errorf("showDiffFor(%v, %q) = %v, want %v", tc.showDiff, tc.output, got, tc.want)
+		}
+	}
+}
+
+func TestParsePackages(t *testing.T) {
+	testCases := []struct {
+		name     string
+		packages string
+		replaces string
+		bumpFile string
+		want     map[string]*types.Package
+		wantErr  string
+	}{
+		{
+			name:    "nothing provided",
+			wantErr: "no packages or replaces provided",
+		},
+		{
+			name:     "packages and bump file",
+			packages: "github.com/google/uuid@v1.4.0",
+			bumpFile: "bumps.yaml",
+			wantErr:  "both --packages and --bump-file",
+		},
+		{
+			name:     "invalid package",
+			packages: "github.com/google/uuid",
+			wantErr:  "invalid package format",
+		},
+		{
+			name:     "invalid version query",
+			packages: "github.com/google/uuid@^1.4",
+			wantErr:  "invalid version query",
+		},
+		{
+			name:     "version queries",
+			packages: "github.com/google/uuid@latest golang.org/x/mod@>=v0.17.0,<v0.20",
+			want: map[string]*types.Package{
+				"github.com/google/uuid": {
+					Name:    "github.com/google/uuid",
+					Version: "latest",
+				},
+				"golang.org/x/mod": {
+					Name:    "golang.org/x/mod",
+					Version: ">=v0.17.0,<v0.20",
+					Index:   1,
+				},
+			},
+		},
+		{
+			name:     "packages and replaces",
+			packages: "github.com/google/uuid@v1.4.0",
+			replaces: "github.com/google/gofuzz=github.com/fakefuzz@v1.2.3",
+			want: map[string]*types.Package{
+				"github.com/google/uuid": {
+					Name:    "github.com/google/uuid",
+					Version: "v1.4.0",
+				},
+				"github.com/fakefuzz": {
+					OldName: "github.com/google/gofuzz",
+					Name:    "github.com/fakefuzz",
+					Version: "v1.2.3",
+					Replace: true,
+				},
+			},
+		},
+		{
+			name:     "version-specific replace",
+			replaces: "github.com/google/gofuzz@v1.0.0=github.com/fakefuzz@v1.2.3",
+			want: map[string]*types.Package{
+				"github.com/fakefuzz": {
+					OldName:    "github.com/google/gofuzz",
+					OldVersion: "v1.0.0",
+					Name:       "github.com/fakefuzz",
+					Version:    "v1.2.3",
+					Replace:    true,
+				},
+			},
+		},
+		{
+			name:     "invalid replaced version",
+			replaces: "github.com/google/gofuzz@latest=github.com/fakefuzz@v1.2.3",
+			wantErr:  "is not a semantic version",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _, err := parsePackages(tc.packages, tc.replaces, tc.bumpFile)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("parsePackages() = %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePackages() = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("parsePackages() (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckCmdRegistered(t *testing.T) {
+	cmd, _, err := RootCmd().Find([]string{"check"})
+	if err != nil || cmd.Name() != "check" {
+		t.Fatalf("check command not found: %v", err)
+	}
+	for _, name := range []string{"bump-file", "packages", "replaces", "modroot", "output"} {
+		if cmd.Flags().Lookup(name) == nil {
+			t.Errorf("check command is missing the --%s flag", name)
+		}
+	}
+}
+
+func TestVulnCmdRegistered(t *testing.T) {
+	cmd, _, err := RootCmd().Find([]string{"vuln"})
+	if err != nil || cmd.Name() != "vuln" {
+		t.Fatalf("vuln command not found: %v", err)
+	}
+	for _, name := range []string{"osv-dir", "govulncheck-file", "govulncheck-after", "modroot", "dry-run", "output"} {
+		if cmd.Flags().Lookup(name) == nil {
+			t.Errorf("vuln command is missing the --%s flag", name)
+		}
+	}
+}
+
+func TestPruneCmdRegistered(t *testing.T) {
+	cmd, _, err := RootCmd().Find([]string{"prune-replaces"})
+	if err != nil || cmd.Name() != "prune-replaces" {
+		t.Fatalf("prune-replaces command not found: %v", err)
+	}
+	for _, name := range []string{"modroot", "tidy", "dry-run", "output"} {
+		if cmd.Flags().Lookup(name) == nil {
+			t.Errorf("prune-replaces command is missing the --%s flag", name)
+		}
+	}
+}
+
+func TestPrintPruned(t *testing.T) {
+	result := &types.PruneResult{
+		DryRun: true,
+		Pruned: []types.PrunedReplace{
+			{Path: "example.com/a", Version: "v1.2.4", Required: "v1.3.0"},
+			{Path: "example.com/c", OldVersion: "v1.1.0", Version: "v1.2.4", Required: "v1.2.0"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := printPruned(&buf, result, outputText); err != nil {
+		t.Fatal(err)
+	}
+	want := "would prune: example.com/a => example.com/a v1.2.4 (requires v1.3.0)\n" +
+		"would prune: example.com/c v1.1.0 => example.com/c v1.2.4 (requires v1.2.0)\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("printPruned() (-want +got)\n%s", diff)
+	}
+}
+
+func TestGoRunner(t *testing.T) {
+	runner, err := goRunner("", nil)
+	if err != nil || runner != nil {
+		t.Errorf("goRunner() = %v, %v, want the default runner", runner, err)
+	}
+
+	runner, err = goRunner("/usr/local/go/bin/go", []string{"GOTOOLCHAIN=local", "GOFLAGS="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &run.ExecRunner{GoBinary: "/usr/local/go/bin/go", GoEnv: &run.GoEnv{Toolchain: "local"}}
+	if diff := cmp.Diff(want, runner); diff != "" {
+		t.Errorf("goRunner() (-want +got)\n%s", diff)
+	}
+
+	if _, err := goRunner("", []string{"GOOS=linux"}); err == nil {
+		t.Error("goRunner() with an unsupported variable succeeded, want an error")
+	}
+}
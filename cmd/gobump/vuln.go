@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+	"github.com/chainguard-dev/gobump/pkg/update"
+	"github.com/chainguard-dev/gobump/pkg/vuln"
+	"github.com/spf13/cobra"
+)
+
+type vulnCLIFlags struct {
+	osvDir           string
+	govulncheckFile  string
+	govulncheckAfter string
+	modroot          string
+	goVersion        string
+	maxGoVersion     string
+	goVersionSource  []string
+	goBinary         string
+	goEnv            []string
+	tidy             bool
+	showDiff         bool
+	dryRun           bool
+	transactional    bool
+	annotate         bool
+	output           string
+}
+
+var vulnFlags vulnCLIFlags
+
+// vulnCmd bumps the modules affected by known vulnerabilities to their fixed versions.
+var vulnCmd = &cobra.Command{
+	Use:          "vuln",
+	Short:        "Bump the vulnerable modules to the lowest version fixing them",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if vulnFlags.output != outputText && vulnFlags.output != outputJSON {
+			return fmt.Errorf("invalid output format %q. Use %q or %q", vulnFlags.output, outputText, outputJSON)
+		}
+		if vulnFlags.osvDir == "" && vulnFlags.govulncheckFile == "" {
+			return fmt.Errorf("no advisories provided. Use --osv-dir or --govulncheck-file")
+		}
+		if vulnFlags.osvDir != "" && vulnFlags.govulncheckFile != "" {
+			return fmt.Errorf("both --osv-dir and --govulncheck-file flags are provided. Use only one")
+		}
+		if vulnFlags.govulncheckAfter != "" {
+			if vulnFlags.govulncheckFile == "" {
+				return fmt.Errorf("--govulncheck-after needs the findings before the bump in --govulncheck-file")
+			}
+			return printResolved(cmd.OutOrStdout(), vulnFlags.govulncheckFile, vulnFlags.govulncheckAfter, vulnFlags.output)
+		}
+
+		var pkgVersions map[string]*types.Package
+		var err error
+		if vulnFlags.govulncheckFile != "" {
+			pkgVersions, err = govulncheckPackages(vulnFlags.govulncheckFile)
+		} else {
+			pkgVersions, err = osvPackages(vulnFlags.osvDir, vulnFlags.modroot)
+		}
+		if err != nil {
+			return err
+		}
+		if len(pkgVersions) == 0 {
+			log.Println("No vulnerable module with a fixed version was found")
+			return nil
+		}
+
+		runner, err := goRunner(vulnFlags.goBinary, vulnFlags.goEnv)
+		if err != nil {
+			return err
+		}
+		result, err := update.DoUpdateContext(cmd.Context(), pkgVersions, &types.Config{
+			Modroot:          vulnFlags.modroot,
+			Tidy:             vulnFlags.tidy,
+			GoVersion:        vulnFlags.goVersion,
+			GoVersionSources: goVersionSources(vulnFlags.goVersionSource),
+			ShowDiff:         showDiffFor(vulnFlags.showDiff, vulnFlags.output),
+			DryRun:           vulnFlags.dryRun,
+			Transactional:    vulnFlags.transactional,
+			MaxGoVersion:     vulnFlags.maxGoVersion,
+			Annotate:         vulnFlags.annotate,
+			Runner:           runner,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to run update. Error: %w", err)
+		}
+		return printResult(cmd.OutOrStdout(), result, vulnFlags.output, vulnFlags.showDiff)
+	},
+}
+
+// osvPackages returns the packages to bump to fix the advisories in osvDir affecting the module in modroot.
+func osvPackages(osvDir, modroot string) (map[string]*types.Package, error) {
+	entries, err := vuln.LoadDir(osvDir)
+	if err != nil {
+		return nil, err
+	}
+	modFile, _, err := update.ParseGoModfile(path.Join(modroot, "go.mod"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the go mod file with error: %w", err)
+	}
+	fixes := vuln.Fixes(entries, modFile)
+	logFixes(fixes)
+	return vuln.Packages(fixes), nil
+}
+
+// govulncheckPackages returns the packages to bump to fix the reachable findings of a 'govulncheck -json' output.
+func govulncheckPackages(file string) (map[string]*types.Package, error) {
+	report, err := vuln.ParseGovulncheckFile(file)
+	if err != nil {
+		return nil, err
+	}
+	fixes := report.Fixes()
+	logFixes(fixes)
+	return vuln.Packages(fixes), nil
+}
+
+func logFixes(fixes []vuln.Fix) {
+	for _, fix := range fixes {
+		if fix.FixedVersion != "" {
+			log.Printf("%s@%s is affected by %s, fixed in %s\n", fix.Module, fix.Version, strings.Join(fix.IDs, ", "), fix.FixedVersion)
+		}
+		if len(fix.Unfixed) > 0 {
+			log.Printf("Warning: %s@%s is affected by %s, which have no fixed version\n", fix.Module, fix.Version, strings.Join(fix.Unfixed, ", "))
+		}
+	}
+}
+
+// printResolved compares the govulncheck findings before and after a bump, and writes the
+// reachable advisories that were resolved and the ones that remain.
+func printResolved(w io.Writer, beforeFile, afterFile, output string) error {
+	before, err := vuln.ParseGovulncheckFile(beforeFile)
+	if err != nil {
+		return err
+	}
+	after, err := vuln.ParseGovulncheckFile(afterFile)
+	if err != nil {
+		return err
+	}
+	resolved, remaining := vuln.Compare(before, after)
+
+	if output == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Resolved  []string `json:"resolved"`
+			Remaining []string `json:"remaining"`
+		}{append([]string{}, resolved...), append([]string{}, remaining...)})
+	}
+	for _, id := range resolved {
+		if _, err := fmt.Fprintf(w, "resolved: %s\n", id); err != nil {
+			return err
+		}
+	}
+	for _, id := range remaining {
+		if _, err := fmt.Fprintf(w, "remaining: %s\n", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(vulnCmd)
+
+	flagSet := vulnCmd.Flags()
+	flagSet.StringVar(&vulnFlags.osvDir, "osv-dir", "", "Directory holding OSV advisories in JSON, such as an export of the Go vulnerability database")
+	flagSet.StringVar(&vulnFlags.govulncheckFile, "govulncheck-file", "", "Output of 'govulncheck -json', the modules with reachable findings are bumped to their fixed version")
+	flagSet.StringVar(&vulnFlags.govulncheckAfter, "govulncheck-after", "", "Output of 'govulncheck -json' after the bump, print the findings of --govulncheck-file that were resolved and the ones that remain instead of bumping")
+	flagSet.StringVar(&vulnFlags.modroot, "modroot", "", "path to the go.mod root")
+	flagSet.StringVar(&vulnFlags.goBinary, "go-binary", "", "The go command to run, defaults to go from PATH")
+	flagSet.StringArrayVar(&vulnFlags.goEnv, "go-env", nil, "KEY=VALUE for the go commands, one of GOTOOLCHAIN, GOFLAGS, GOPROXY, GONOSUMDB, GOPRIVATE, GOMODCACHE or GOWORK. Can be repeated. When set, none of these variables is inherited from the environment")
+	flagSet.StringVar(&vulnFlags.goVersion, "go-version", "", "set the go-version for go-mod-tidy")
+	flagSet.StringSliceVar(&vulnFlags.goVersionSource, "go-version-source", nil, "Comma-separated sources of the go version for go-mod-tidy, tried in order: flag, env, gomod, gowork, toolchain. Defaults to flag,env")
+	flagSet.StringVar(&vulnFlags.maxGoVersion, "max-go-version", "", "Fail if the update raises the go or toolchain line of go.mod above this go version (e.g. 1.22), naming the bumped packages requiring it")
+	flagSet.BoolVar(&vulnFlags.tidy, "tidy", false, "Run 'go mod tidy' command")
+	flagSet.BoolVar(&vulnFlags.showDiff, "show-diff", false, "Show the difference between the original and 'go.mod' files")
+	flagSet.BoolVar(&vulnFlags.dryRun, "dry-run", false, "Run the update against a scratch copy of the modroot and show what would change without modifying it")
+	flagSet.BoolVar(&vulnFlags.transactional, "transactional", false, "Restore go.mod, go.sum, go.work, go.work.sum and vendor/modules.txt if the update fails")
+	flagSet.BoolVar(&vulnFlags.annotate, "annotate", false, "Write the advisories fixed by every bumped module as a '// gobump: <IDs>' comment on its require or replace line of go.mod")
+	flagSet.StringVar(&vulnFlags.output, "output", outputText, "Output format of the update report, one of 'text' or 'json'")
+}
@@ -2,13 +2,27 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	cmd "github.com/chainguard-dev/gobump/cmd/gobump"
 )
 
 func main() {
-	if err := cmd.RootCmd().Execute(); err != nil {
+	// Cancel on interrupt so an update in progress gets rolled back.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := cmd.RootCmd().ExecuteContext(ctx)
+	stop()
+	if err != nil {
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			log.Print(exitErr)
+			os.Exit(exitErr.Code)
+		}
 		log.Fatal(err)
 	}
 }
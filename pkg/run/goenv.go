@@ -0,0 +1,83 @@
+package run
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GoEnv is the environment of the go toolchain for the commands of an ExecRunner. The variables it
+// covers are never inherited from the process, so an empty field leaves the go default in place.
+type GoEnv struct {
+	Toolchain string // GOTOOLCHAIN
+	Flags     string // GOFLAGS
+	Proxy     string // GOPROXY
+	NoSumDB   string // GONOSUMDB
+	Private   string // GOPRIVATE
+	ModCache  string // GOMODCACHE
+	Work      string // GOWORK
+}
+
+// ParseGoEnv builds a GoEnv from KEY=VALUE entries, e.g. GOTOOLCHAIN=local.
+func ParseGoEnv(entries []string) (*GoEnv, error) {
+	env := &GoEnv{}
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid go environment entry %q, it should look like KEY=VALUE", entry)
+		}
+		field := env.field(key)
+		if field == nil {
+			return nil, fmt.Errorf("unsupported go environment variable %q, use one of %s", key, strings.Join(goEnvKeys, ", "))
+		}
+		*field = value
+	}
+	return env, nil
+}
+
+// goEnvKeys are the variables covered by GoEnv.
+var goEnvKeys = []string{"GOTOOLCHAIN", "GOFLAGS", "GOPROXY", "GONOSUMDB", "GOPRIVATE", "GOMODCACHE", "GOWORK"}
+
+func (e *GoEnv) field(key string) *string {
+	switch key {
+	case "GOTOOLCHAIN":
+		return &e.Toolchain
+	case "GOFLAGS":
+		return &e.Flags
+	case "GOPROXY":
+		return &e.Proxy
+	case "GONOSUMDB":
+		return &e.NoSumDB
+	case "GOPRIVATE":
+		return &e.Private
+	case "GOMODCACHE":
+		return &e.ModCache
+	case "GOWORK":
+		return &e.Work
+	}
+	return nil
+}
+
+// lookup returns the value of key and whether GoEnv covers it.
+func (e *GoEnv) lookup(key string) (string, bool) {
+	if field := e.field(key); field != nil {
+		return *field, true
+	}
+	return "", false
+}
+
+// environ returns base without the variables covered by e, followed by the ones e sets.
+func (e *GoEnv) environ(base []string) []string {
+	env := make([]string, 0, len(base)+len(goEnvKeys))
+	for _, kv := range base {
+		key, _, _ := strings.Cut(kv, "=")
+		if e.field(key) == nil {
+			env = append(env, kv)
+		}
+	}
+	for _, key := range goEnvKeys {
+		if value := *e.field(key); value != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
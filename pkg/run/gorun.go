@@ -2,25 +2,24 @@
 package run
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
 
 	versionutil "k8s.io/apimachinery/pkg/util/version"
 )
 
 // GoModTidy runs go mod tidy with the specified go version and compatibility settings.
-func GoModTidy(modroot, goVersion, compat string) (string, error) {
+func GoModTidy(ctx context.Context, r Runner, modroot, goVersion, compat string) (string, error) {
 	if goVersion == "" {
-		// Use runtime.Version() instead of exec.Command
-		goVersion = strings.TrimPrefix(runtime.Version(), "go")
-
-		v := versionutil.MustParseGeneric(goVersion)
-		goVersion = fmt.Sprintf("%d.%d", v.Major(), v.Minor())
+		var err error
+		if goVersion, err = defaultGoVersion(ctx, r, modroot); err != nil {
+			return "", err
+		}
 	}
 
 	log.Printf("Running go mod tidy with go version '%s' ...\n", goVersion)
@@ -30,14 +29,37 @@ func GoModTidy(modroot, goVersion, compat string) (string, error) {
 		args = append(args, "-compat", compat)
 	}
 
-	cmd := exec.Command("go", args...) //nolint:gosec
-	cmd.Dir = modroot
-	if bytes, err := cmd.CombinedOutput(); err != nil {
+	if bytes, err := goCommand(ctx, r, modroot, args...); err != nil {
 		return strings.TrimSpace(string(bytes)), err
 	}
 	return "", nil
 }
 
+// GoEnvVersion returns the version of the go command run in dir, e.g. go1.22.5, which is
+// the toolchain GOTOOLCHAIN selects for the module rather than the one gobump was built with.
+func GoEnvVersion(ctx context.Context, r Runner, dir string) (string, error) {
+	bytes, err := goCommand(ctx, r, dir, "env", "GOVERSION")
+	if err != nil {
+		return "", fmt.Errorf("failed to run 'go env GOVERSION': %w, output: %s", err, strings.TrimSpace(string(bytes)))
+	}
+	// A toolchain switch logs its download first, the version is the last line.
+	lines := strings.Split(strings.TrimSpace(string(bytes)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1]), nil
+}
+
+// defaultGoVersion returns the major.minor version of the go command run in dir.
+func defaultGoVersion(ctx context.Context, r Runner, dir string) (string, error) {
+	goVersion, err := GoEnvVersion(ctx, r, dir)
+	if err != nil {
+		return "", err
+	}
+	v, err := versionutil.ParseGeneric(strings.TrimPrefix(goVersion, "go"))
+	if err != nil {
+		return "", fmt.Errorf("unexpected go version %q: %w", goVersion, err)
+	}
+	return fmt.Sprintf("%d.%d", v.Major(), v.Minor()), nil
+}
+
 func findWorkspaceFile(dir string) (root string) {
 	dir = filepath.Clean(dir)
 	// Look for enclosing go.mod.
@@ -55,8 +77,8 @@ func findWorkspaceFile(dir string) (root string) {
 	return ""
 }
 
-func findGoWork(modroot string) string {
-	switch gowork := os.Getenv("GOWORK"); gowork {
+func findGoWork(r Runner, modroot string) string {
+	switch gowork := Getenv(r, "GOWORK"); gowork {
 	case "off":
 		return ""
 	case "", "auto":
@@ -66,11 +88,17 @@ func findGoWork(modroot string) string {
 	}
 }
 
+// FindGoWork returns the path of the go.work file that applies to modroot, honoring the GOWORK
+// of the commands of r. It returns an empty string when the module is not part of a workspace.
+func FindGoWork(r Runner, modroot string) string {
+	return findGoWork(r, modroot)
+}
+
 // UpdateGoWorkVersion updates the go.work version if we're using workspaces.
 // This should be called early before any go commands to avoid version mismatch errors.
-func UpdateGoWorkVersion(modroot string, forceWork bool, goVersion string) error {
+func UpdateGoWorkVersion(ctx context.Context, r Runner, modroot string, forceWork bool, goVersion string) error {
 	// Find go.work file if it exists
-	workPath := findGoWork(modroot)
+	workPath := findGoWork(r, modroot)
 	if !forceWork && workPath == "" {
 		// No workspace and not forcing, nothing to do
 		return nil
@@ -78,7 +106,7 @@ func UpdateGoWorkVersion(modroot string, forceWork bool, goVersion string) error
 
 	if workPath == "" && forceWork {
 		// Try current directory if --work flag is used
-		workPath = findGoWork(".")
+		workPath = findGoWork(r, ".")
 	}
 
 	if workPath == "" {
@@ -87,17 +115,16 @@ func UpdateGoWorkVersion(modroot string, forceWork bool, goVersion string) error
 	}
 
 	// Auto-detect Go version if not provided
+	dir := filepath.Dir(workPath)
 	if goVersion == "" {
-		goVersion = strings.TrimPrefix(runtime.Version(), "go")
-		v := versionutil.MustParseGeneric(goVersion)
-		goVersion = fmt.Sprintf("%d.%d", v.Major(), v.Minor())
+		var err error
+		if goVersion, err = defaultGoVersion(ctx, r, dir); err != nil {
+			return err
+		}
 	}
 
 	log.Printf("Updating go.work version to %s...\n", goVersion)
-	dir := filepath.Dir(workPath)
-	cmd := exec.Command("go", "work", "edit", "-go", goVersion)
-	cmd.Dir = dir
-	if bytes, err := cmd.CombinedOutput(); err != nil {
+	if bytes, err := goCommand(ctx, r, dir, "work", "edit", "-go", goVersion); err != nil {
 		return fmt.Errorf("failed to update go.work version: %w, output: %s", err, strings.TrimSpace(string(bytes)))
 	}
 
@@ -105,18 +132,16 @@ func UpdateGoWorkVersion(modroot string, forceWork bool, goVersion string) error
 }
 
 // GoVendor runs go mod vendor or go work vendor depending on workspace configuration.
-func GoVendor(dir string, forceWork bool) (string, error) {
-	workPath := findGoWork(dir)
+func GoVendor(ctx context.Context, r Runner, dir string, forceWork bool) (string, error) {
+	workPath := findGoWork(r, dir)
 	if forceWork || workPath != "" {
 		log.Print("Running go work vendor...")
-		cmd := exec.Command("go", "work", "vendor")
-		if bytes, err := cmd.CombinedOutput(); err != nil {
+		if bytes, err := goCommand(ctx, r, dir, "work", "vendor"); err != nil {
 			return strings.TrimSpace(string(bytes)), err
 		}
 	} else {
 		log.Print("Running go mod vendor...")
-		cmd := exec.Command("go", "mod", "vendor")
-		if bytes, err := cmd.CombinedOutput(); err != nil {
+		if bytes, err := goCommand(ctx, r, dir, "mod", "vendor"); err != nil {
 			return strings.TrimSpace(string(bytes)), err
 		}
 	}
@@ -125,52 +150,105 @@ func GoVendor(dir string, forceWork bool) (string, error) {
 }
 
 // GoGetModule runs go get for a specific module and version.
-func GoGetModule(name, version, modroot string) (string, error) {
-	cmd := exec.Command("go", "get", fmt.Sprintf("%s@%s", name, version)) //nolint:gosec
-	cmd.Dir = modroot
-	if bytes, err := cmd.CombinedOutput(); err != nil {
+func GoGetModule(ctx context.Context, r Runner, name, version, modroot string) (string, error) {
+	if bytes, err := goCommand(ctx, r, modroot, "get", fmt.Sprintf("%s@%s", name, version)); err != nil {
+		return strings.TrimSpace(string(bytes)), err
+	}
+	return "", nil
+}
+
+// GoGetModules runs a single go get for all the given module@version queries.
+func GoGetModules(ctx context.Context, r Runner, modroot string, modules ...string) (string, error) {
+	if bytes, err := goCommand(ctx, r, modroot, append([]string{"get"}, modules...)...); err != nil {
 		return strings.TrimSpace(string(bytes)), err
 	}
 	return "", nil
 }
 
 // GoModEditReplaceModule edits go.mod to replace one module with another.
-func GoModEditReplaceModule(nameOld, nameNew, version, modroot string) (string, error) {
-	cmd := exec.Command("go", "mod", "edit", "-dropreplace", nameOld) //nolint:gosec
-	cmd.Dir = modroot
-	if bytes, err := cmd.CombinedOutput(); err != nil {
+func GoModEditReplaceModule(ctx context.Context, r Runner, nameOld, nameNew, version, modroot string) (string, error) {
+	if bytes, err := goCommand(ctx, r, modroot, "mod", "edit", "-dropreplace", nameOld); err != nil {
 		return strings.TrimSpace(string(bytes)), fmt.Errorf("error running go command to drop replace modules: %w", err)
 	}
 
-	cmd = exec.Command("go", "mod", "edit", "-replace", fmt.Sprintf("%s=%s@%s", nameOld, nameNew, version)) //nolint:gosec
-	cmd.Dir = modroot
-	if bytes, err := cmd.CombinedOutput(); err != nil {
+	if bytes, err := goCommand(ctx, r, modroot, "mod", "edit", "-replace", fmt.Sprintf("%s=%s@%s", nameOld, nameNew, version)); err != nil {
 		return strings.TrimSpace(string(bytes)), fmt.Errorf("error running go command to replace modules: %w", err)
 	}
 	return "", nil
 }
 
+// GoWorkEditReplaceModule edits go.work to replace one module with another.
+func GoWorkEditReplaceModule(ctx context.Context, r Runner, nameOld, nameNew, version, workdir string) (string, error) {
+	if bytes, err := goCommand(ctx, r, workdir, "work", "edit", "-dropreplace", nameOld); err != nil {
+		return strings.TrimSpace(string(bytes)), fmt.Errorf("error running go command to drop workspace replace modules: %w", err)
+	}
+
+	if bytes, err := goCommand(ctx, r, workdir, "work", "edit", "-replace", fmt.Sprintf("%s=%s@%s", nameOld, nameNew, version)); err != nil {
+		return strings.TrimSpace(string(bytes)), fmt.Errorf("error running go command to replace workspace modules: %w", err)
+	}
+	return "", nil
+}
+
+// GoWorkSync runs go work sync in the directory of the go.work file.
+func GoWorkSync(ctx context.Context, r Runner, workdir string) (string, error) {
+	if bytes, err := goCommand(ctx, r, workdir, "work", "sync"); err != nil {
+		return strings.TrimSpace(string(bytes)), err
+	}
+	return "", nil
+}
+
 // GoModEditDropRequireModule drops a require directive from go.mod.
-func GoModEditDropRequireModule(name, modroot string) (string, error) {
-	cmd := exec.Command("go", "mod", "edit", "-droprequire", name) //nolint:gosec
-	cmd.Dir = modroot
-	if bytes, err := cmd.CombinedOutput(); err != nil {
+func GoModEditDropRequireModule(ctx context.Context, r Runner, name, modroot string) (string, error) {
+	if bytes, err := goCommand(ctx, r, modroot, "mod", "edit", "-droprequire", name); err != nil {
 		return strings.TrimSpace(string(bytes)), err
 	}
 
 	return "", nil
 }
 
-// GoModEditRequireModule adds or updates a require directive in go.mod.
-func GoModEditRequireModule(name, version, modroot string) (string, error) {
-	if bytes, err := GoModEditDropRequireModule(name, modroot); err != nil {
+// GoModEditDropReplaceModule drops the replace of name, or of name@version only, from go.mod.
+func GoModEditDropReplaceModule(ctx context.Context, r Runner, name, modroot string) (string, error) {
+	if bytes, err := goCommand(ctx, r, modroot, "mod", "edit", "-dropreplace", name); err != nil {
 		return strings.TrimSpace(string(bytes)), err
 	}
+	return "", nil
+}
 
-	cmd := exec.Command("go", "mod", "edit", "-require", fmt.Sprintf("%s@%s", name, version)) //nolint:gosec
-	cmd.Dir = modroot
-	if bytes, err := cmd.CombinedOutput(); err != nil {
+// GoModEditRequireModule adds or updates a require directive in go.mod. An existing require is
+// updated in place, keeping its block, its comments and its indirect marker.
+func GoModEditRequireModule(ctx context.Context, r Runner, name, version, modroot string) (string, error) {
+	if bytes, err := goCommand(ctx, r, modroot, "mod", "edit", "-require", fmt.Sprintf("%s@%s", name, version)); err != nil {
 		return strings.TrimSpace(string(bytes)), err
 	}
 	return "", nil
 }
+
+// ModuleInfo is the subset of the 'go list -m -json' output used by gobump.
+type ModuleInfo struct {
+	Path      string
+	Version   string
+	Versions  []string
+	GoVersion string
+}
+
+// GoListModule runs 'go list -m -json' for the module query (e.g. "example.com/mod@latest")
+// in modroot. When versions is set, the known versions of the module are listed too.
+func GoListModule(ctx context.Context, r Runner, modroot, query string, versions bool) (*ModuleInfo, error) {
+	args := []string{"list", "-m", "-json"}
+	if findGoWork(r, modroot) == "" {
+		// A vendor directory would otherwise make the query fail, workspaces refuse the flag.
+		args = append(args, "-mod=mod")
+	}
+	if versions {
+		args = append(args, "-versions")
+	}
+	bytes, err := goCommand(ctx, r, modroot, append(args, query)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list module %s: %w, output: %s", query, err, strings.TrimSpace(string(bytes)))
+	}
+	var info ModuleInfo
+	if err := json.Unmarshal(bytes, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse 'go list' output for module %s: %w", query, err)
+	}
+	return &info, nil
+}
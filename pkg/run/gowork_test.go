@@ -1,6 +1,7 @@
 package run
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -88,7 +89,7 @@ func TestGoWork(t *testing.T) {
 					workDir = filepath.Join(tmpDir, "subdir")
 				}
 
-				result := findGoWork(workDir)
+				result := findGoWork(nil, workDir)
 
 				switch tc.expectedPath {
 				case "":
@@ -175,7 +176,7 @@ replace example.com/old => ./new`,
 
 				// For tests, we call UpdateGoWorkVersion with the directory containing go.work
 				// and forceWork=true since we know we want to update it
-				err := UpdateGoWorkVersion(filepath.Dir(workPath), true, tc.goVersion)
+				err := UpdateGoWorkVersion(context.Background(), nil, filepath.Dir(workPath), true, tc.goVersion)
 				if err != nil {
 					t.Fatalf("Failed to update go.work: %v", err)
 				}
@@ -274,7 +275,7 @@ use .`
 				}
 
 				// Call GoVendor
-				_, _ = GoVendor(tmpDir, tc.forceWork)
+				_, _ = GoVendor(context.Background(), nil, tmpDir, tc.forceWork)
 
 				// Test passes if no panic (we can't easily test the actual command executed)
 			})
@@ -0,0 +1,124 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Runner runs go commands on behalf of the helpers in this package.
+type Runner interface {
+	// Run runs the go command with args in dir and returns its combined output.
+	// The command must be stopped when ctx is done.
+	Run(ctx context.Context, dir string, args ...string) ([]byte, error)
+}
+
+// ExecRunner is the default Runner, it executes the go binary with os/exec.
+type ExecRunner struct {
+	// GoBinary is the go command to execute. Defaults to "go", looked up in PATH.
+	GoBinary string
+	// GoEnv, when set, is the environment of the go toolchain used instead of the one of the process.
+	GoEnv *GoEnv
+	// Env holds extra KEY=VALUE entries added to the environment of every command.
+	Env []string
+}
+
+// Run implements Runner. When the command fails because ctx is done, the returned error wraps ctx.Err().
+func (r *ExecRunner) Run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	bin := r.GoBinary
+	if bin == "" {
+		bin = "go"
+	}
+	cmd := exec.CommandContext(ctx, bin, args...) //nolint:gosec
+	cmd.Dir = dir
+	if r.GoEnv != nil {
+		cmd.Env = append(r.GoEnv.environ(os.Environ()), r.Env...)
+	} else if len(r.Env) > 0 {
+		cmd.Env = append(os.Environ(), r.Env...)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil && ctx.Err() != nil {
+		return out, fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+	return out, err
+}
+
+// Getenv returns the value of the environment variable key for the commands of r.
+func (r *ExecRunner) Getenv(key string) string {
+	for i := len(r.Env) - 1; i >= 0; i-- {
+		if k, v, ok := strings.Cut(r.Env[i], "="); ok && k == key {
+			return v
+		}
+	}
+	if r.GoEnv != nil {
+		if v, ok := r.GoEnv.lookup(key); ok {
+			return v
+		}
+	}
+	return os.Getenv(key)
+}
+
+// Getenv returns the value of the environment variable key for the commands run by r. Runners
+// setting the environment of their commands tell it with a Getenv method, like ExecRunner.
+func Getenv(r Runner, key string) string {
+	if e, ok := r.(interface{ Getenv(string) string }); ok {
+		return e.Getenv(key)
+	}
+	return os.Getenv(key)
+}
+
+// CommandError is returned by the helpers of this package when a go command fails.
+type CommandError struct {
+	// Dir is the directory the command ran in.
+	Dir string
+	// Args are the arguments passed to the go command.
+	Args []string
+	// Output is the combined output of the command, trimmed of surrounding whitespace.
+	Output string
+	// ExitCode is the exit code of the command, or -1 if it did not exit normally
+	// (e.g. it could not be started or was killed).
+	ExitCode int
+	// Err is the error returned by the Runner.
+	Err error
+}
+
+// Error returns the message of the underlying error, the output is available in Output.
+func (e *CommandError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error returned by the Runner.
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// goCommand runs the go command with args in dir with r, wrapping any failure in a *CommandError.
+func goCommand(ctx context.Context, r Runner, dir string, args ...string) ([]byte, error) {
+	out, err := orDefault(r).Run(ctx, dir, args...)
+	if err != nil {
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		return out, &CommandError{
+			Dir:      dir,
+			Args:     args,
+			Output:   strings.TrimSpace(string(out)),
+			ExitCode: exitCode,
+			Err:      err,
+		}
+	}
+	return out, nil
+}
+
+// orDefault returns r, or an ExecRunner running "go" from PATH when r is nil.
+func orDefault(r Runner) Runner {
+	if r == nil {
+		return &ExecRunner{}
+	}
+	return r
+}
@@ -0,0 +1,126 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecRunner(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh command not found, skipping test")
+	}
+	tmpDir := t.TempDir()
+	// A stray caller setting must not leak into the commands of a runner with a GoEnv.
+	t.Setenv("GOFLAGS", "-mod=vendor")
+
+	testCases := []struct {
+		name   string
+		runner *ExecRunner
+		args   []string
+		want   string
+	}{
+		{
+			name:   "custom binary",
+			runner: &ExecRunner{GoBinary: "echo"},
+			args:   []string{"mod", "tidy"},
+			want:   "mod tidy",
+		},
+		{
+			name:   "runs in dir",
+			runner: &ExecRunner{GoBinary: "sh"},
+			args:   []string{"-c", "pwd"},
+			want:   tmpDir,
+		},
+		{
+			name:   "extra environment",
+			runner: &ExecRunner{GoBinary: "sh", Env: []string{"GOBUMP_TEST=hello"}},
+			args:   []string{"-c", "echo $GOBUMP_TEST"},
+			want:   "hello",
+		},
+		{
+			name:   "inherited environment",
+			runner: &ExecRunner{GoBinary: "sh"},
+			args:   []string{"-c", "echo $GOFLAGS"},
+			want:   "-mod=vendor",
+		},
+		{
+			name:   "go environment",
+			runner: &ExecRunner{GoBinary: "sh", GoEnv: &GoEnv{Toolchain: "local"}, Env: []string{"GOBUMP_TEST=hello"}},
+			args:   []string{"-c", "echo \"$GOFLAGS|$GOTOOLCHAIN|$GOBUMP_TEST\""},
+			want:   "|local|hello",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := tc.runner.Run(context.Background(), tmpDir, tc.args...)
+			if err != nil {
+				t.Fatalf("Run() = %v, output: %s", err, out)
+			}
+			if got := strings.TrimSpace(string(out)); got != tc.want {
+				t.Errorf("Run() output: got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseGoEnv(t *testing.T) {
+	env, err := ParseGoEnv([]string{"GOTOOLCHAIN=local", "GOMODCACHE=/cache", "GOWORK=off"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (GoEnv{Toolchain: "local", ModCache: "/cache", Work: "off"}); *env != want {
+		t.Errorf("ParseGoEnv() = %+v, want %+v", *env, want)
+	}
+
+	r := &ExecRunner{GoEnv: env}
+	if got := Getenv(r, "GOWORK"); got != "off" {
+		t.Errorf("Getenv(GOWORK) = %q, want %q", got, "off")
+	}
+
+	for _, entries := range [][]string{{"GOTOOLCHAIN"}, {"GOOS=linux"}} {
+		if _, err := ParseGoEnv(entries); err == nil {
+			t.Errorf("ParseGoEnv(%q) succeeded, want an error", entries)
+		}
+	}
+}
+
+func TestExecRunnerCanceled(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep command not found, skipping test")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	r := &ExecRunner{GoBinary: "sleep"}
+	_, err := r.Run(ctx, t.TempDir(), "10")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() = %v, want an error wrapping %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestCommandError(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh command not found, skipping test")
+	}
+	dir := t.TempDir()
+
+	_, err := goCommand(context.Background(), &ExecRunner{GoBinary: "sh"}, dir, "-c", "echo boom; exit 3")
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("goCommand() = %v, want a *CommandError", err)
+	}
+	if cmdErr.ExitCode != 3 {
+		t.Errorf("exit code: got %d, want 3", cmdErr.ExitCode)
+	}
+	if cmdErr.Output != "boom" {
+		t.Errorf("output: got %q, want %q", cmdErr.Output, "boom")
+	}
+	if cmdErr.Dir != dir || len(cmdErr.Args) != 2 || cmdErr.Args[0] != "-c" {
+		t.Errorf("unexpected command: dir %q, args %q", cmdErr.Dir, cmdErr.Args)
+	}
+}
@@ -9,19 +9,27 @@ import (
 	"os"
 
 	"github.com/ghodss/yaml"
+	"golang.org/x/mod/semver"
 )
 
 // ParseFile parses a YAML file containing package update specifications.
 func ParseFile(bumpFile string) (map[string]*Package, error) {
+	pkgVersions, _, err := ParseFileWithDirectives(bumpFile)
+	return pkgVersions, err
+}
+
+// ParseFileWithDirectives is like ParseFile, but also returns the go and toolchain directives
+// requested by the file.
+func ParseFileWithDirectives(bumpFile string) (map[string]*Package, Directives, error) {
 	if bumpFile == "" {
-		return nil, fmt.Errorf("no filename specified")
+		return nil, Directives{}, fmt.Errorf("no filename specified")
 	}
 	bumpFile = filepath.Clean(bumpFile)
 	var pkgVersions map[string]*Package
 	var packageList PackageList
 	file, err := os.Open(bumpFile) //nolint:gosec
 	if err != nil {
-		return nil, fmt.Errorf("failed reading file: %w", err)
+		return nil, Directives{}, fmt.Errorf("failed reading file: %w", err)
 	}
 	defer func() {
 		if err := file.Close(); err != nil {
@@ -31,14 +39,25 @@ func ParseFile(bumpFile string) (map[string]*Package, error) {
 	}()
 	bytes, _ := io.ReadAll(file)
 	if err := yaml.Unmarshal(bytes, &packageList); err != nil {
-		return nil, fmt.Errorf("unmarshaling file: %w", err)
+		return nil, Directives{}, fmt.Errorf("unmarshaling file: %w", err)
 	}
 	for i, p := range packageList.Packages {
 		if p.Name == "" {
-			return nil, fmt.Errorf("invalid package spec at [%d], missing name", i)
+			return nil, Directives{}, fmt.Errorf("invalid package spec at [%d], missing name", i)
 		}
 		if p.Version == "" {
-			return nil, fmt.Errorf("invalid package spec at [%d], missing version", i)
+			return nil, Directives{}, fmt.Errorf("invalid package spec at [%d], missing version", i)
+		}
+		if IsVersionQuery(p.Version) {
+			if _, err := ParseVersionQuery(p.Version); err != nil {
+				return nil, Directives{}, fmt.Errorf("invalid package spec at [%d]: %w", i, err)
+			}
+		}
+		if p.OldVersion != "" && !semver.IsValid(p.OldVersion) {
+			return nil, Directives{}, fmt.Errorf("invalid package spec at [%d], oldVersion %q is not a semantic version", i, p.OldVersion)
+		}
+		if err := validateMode(p); err != nil {
+			return nil, Directives{}, fmt.Errorf("invalid package spec at [%d]: %w", i, err)
 		}
 		if pkgVersions == nil {
 			pkgVersions = make(map[string]*Package, 1)
@@ -46,5 +65,23 @@ func ParseFile(bumpFile string) (map[string]*Package, error) {
 		pkgVersions[p.Name] = &packageList.Packages[i]
 		pkgVersions[p.Name].Index = i
 	}
-	return pkgVersions, nil
+	if err := packageList.Directives.Validate(); err != nil {
+		return nil, Directives{}, fmt.Errorf("invalid directives: %w", err)
+	}
+	return pkgVersions, packageList.Directives, nil
+}
+
+// validateMode checks that the mode of p is known and applies to its version.
+func validateMode(p Package) error {
+	switch p.Mode {
+	case "", VersionModeMinimum:
+		return nil
+	case VersionModeExact, VersionModeLatestPatchAbove:
+		if !semver.IsValid(p.Version) {
+			return fmt.Errorf("mode %q needs a semantic version, got %q", p.Mode, p.Version)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown mode %q. Use %q, %q or %q", p.Mode, VersionModeMinimum, VersionModeExact, VersionModeLatestPatchAbove)
+	}
 }
@@ -12,6 +12,10 @@ const (
 	testFile           = "testdata/bumpfile.yaml"
 	missingNameFile    = "testdata/missingname.yaml"
 	missingVersionFile = "testdata/missingversion.yaml"
+	invalidQueryFile   = "testdata/invalidquery.yaml"
+	invalidModeFile    = "testdata/invalidmode.yaml"
+	modesFile          = "testdata/modes.yaml"
+	invalidOldVersion  = "testdata/invalidoldversion.yaml"
 )
 
 func TestParse(t *testing.T) {
@@ -40,6 +44,25 @@ func TestParse(t *testing.T) {
 		name:     "invalid file",
 		bumpFile: invalidFile,
 		wantErr:  "unmarshaling file",
+	}, {
+		name:     "invalid version query",
+		bumpFile: invalidQueryFile,
+		wantErr:  "not a valid semantic version",
+	}, {
+		name:     "mode on a version query",
+		bumpFile: invalidModeFile,
+		wantErr:  `mode "exact" needs a semantic version`,
+	}, {
+		name:     "invalid old version",
+		bumpFile: invalidOldVersion,
+		wantErr:  `invalid package spec at [1], oldVersion "old-version-2" is not a semantic version`,
+	}, {
+		name:     "modes",
+		bumpFile: modesFile,
+		want: map[string]*Package{
+			"name-1": {Name: "name-1", Version: "v1.2.3", Mode: VersionModeExact},
+			"name-2": {Name: "name-2", Version: "v0.9.1", Mode: VersionModeLatestPatchAbove, Index: 1},
+		},
 	}, {
 		name:     "file",
 		bumpFile: testFile,
@@ -49,9 +72,10 @@ func TestParse(t *testing.T) {
 			Index:   0,
 		},
 			"name-2": {
-				Name:    "name-2",
-				Version: "version-2",
-				Index:   1,
+				Name:       "name-2",
+				Version:    "version-2",
+				OldVersion: "v0.2.0",
+				Index:      1,
 			},
 			"name-3": {
 				Name:    "name-3",
@@ -78,3 +102,17 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFileWithDirectives(t *testing.T) {
+	_, got, err := ParseFileWithDirectives("testdata/directives.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Directives{Go: "1.22.5", Toolchain: "go1.22.6"}); got != want {
+		t.Errorf("ParseFileWithDirectives() directives = %+v, want %+v", got, want)
+	}
+
+	if _, _, err := ParseFileWithDirectives("testdata/invaliddirectives.yaml"); err == nil || !strings.Contains(err.Error(), "invalid go directive") {
+		t.Errorf("ParseFileWithDirectives() = %v, want an invalid go directive error", err)
+	}
+}
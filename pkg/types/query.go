@@ -0,0 +1,183 @@
+package types //nolint:revive
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// QueryKind tells how a version query selects a version.
+type QueryKind string
+
+const (
+	// QueryLatest selects the latest release of the module.
+	QueryLatest QueryKind = "latest"
+	// QueryPatch selects the latest release with the same major and minor version as the current one.
+	QueryPatch QueryKind = "patch"
+	// QueryMinor selects the latest release with the same major version as the current one.
+	QueryMinor QueryKind = "minor"
+	// QueryRange selects the latest release that satisfies a set of semver constraints.
+	QueryRange QueryKind = "range"
+)
+
+// VersionQuery is a parsed version query, such as "latest", "^v1.4" or ">=v1.2.3,<v2".
+type VersionQuery struct {
+	Raw         string
+	Kind        QueryKind
+	constraints []constraint
+}
+
+type constraint struct {
+	op      string
+	version string
+}
+
+// IsVersionQuery tells whether version is a query to resolve rather than a concrete version.
+func IsVersionQuery(version string) bool {
+	switch version {
+	case string(QueryLatest), string(QueryPatch), string(QueryMinor):
+		return true
+	}
+	return strings.ContainsAny(version, "^~<>=,")
+}
+
+// ParseVersionQuery parses a version query. The supported queries are:
+//   - latest, patch and minor, with the same meaning as in 'go get'
+//   - ^v1.4, any release from v1.4.0 below the next major (or minor for v0) version
+//   - ~v0.9.1, any release from v0.9.1 below the next minor version
+//   - a comma-separated list of constraints using >, >=, <, <= and =, e.g. >=v1.2.3,<v2
+func ParseVersionQuery(query string) (*VersionQuery, error) {
+	q := &VersionQuery{Raw: query}
+	switch query {
+	case string(QueryLatest), string(QueryPatch), string(QueryMinor):
+		q.Kind = QueryKind(query)
+		return q, nil
+	}
+
+	q.Kind = QueryRange
+	switch {
+	case strings.HasPrefix(query, "^"):
+		lower, err := queryVersion(query, strings.TrimPrefix(query, "^"))
+		if err != nil {
+			return nil, err
+		}
+		upper := nextMajor(lower)
+		if semver.Major(lower) == "v0" {
+			upper = nextMinor(lower)
+		}
+		q.constraints = []constraint{{">=", lower}, {"<", upper}}
+	case strings.HasPrefix(query, "~"):
+		lower, err := queryVersion(query, strings.TrimPrefix(query, "~"))
+		if err != nil {
+			return nil, err
+		}
+		q.constraints = []constraint{{">=", lower}, {"<", nextMinor(lower)}}
+	default:
+		for _, part := range strings.Split(query, ",") {
+			part = strings.TrimSpace(part)
+			op := strings.TrimRight(part[:min(len(part), 2)], "v0123456789")
+			if op != ">" && op != ">=" && op != "<" && op != "<=" && op != "=" {
+				return nil, fmt.Errorf("invalid version query %q: constraint %q must start with one of >, >=, <, <= or =", query, part)
+			}
+			v, err := queryVersion(query, strings.TrimPrefix(part, op))
+			if err != nil {
+				return nil, err
+			}
+			q.constraints = append(q.constraints, constraint{op, v})
+		}
+	}
+	return q, nil
+}
+
+// queryVersion canonicalizes a version used in a query, so v1.4 becomes v1.4.0.
+func queryVersion(query, version string) (string, error) {
+	if !semver.IsValid(version) {
+		return "", fmt.Errorf("invalid version query %q: %q is not a valid semantic version", query, version)
+	}
+	return semver.Canonical(version), nil
+}
+
+func nextMajor(v string) string {
+	var major int
+	_, _ = fmt.Sscanf(semver.Major(v), "v%d", &major)
+	return fmt.Sprintf("v%d.0.0", major+1)
+}
+
+func nextMinor(v string) string {
+	var major, minor int
+	_, _ = fmt.Sscanf(semver.MajorMinor(v), "v%d.%d", &major, &minor)
+	return fmt.Sprintf("v%d.%d.0", major, minor+1)
+}
+
+// Matches tells whether version satisfies every constraint of a range query.
+// The latest, patch and minor queries match any valid version.
+func (q *VersionQuery) Matches(version string) bool {
+	if !semver.IsValid(version) {
+		return false
+	}
+	for _, c := range q.constraints {
+		cmp := semver.Compare(version, c.version)
+		var ok bool
+		switch c.op {
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case "=":
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Select picks the version the query resolves to. current is the version in go.mod, if any, latest
+// is the version the module proxy reports as latest and versions lists every known version.
+// Pre-releases are only selected by the latest query, when the proxy reports no release.
+func (q *VersionQuery) Select(current, latest string, versions []string) (string, error) {
+	var match func(string) bool
+	switch q.Kind {
+	case QueryLatest:
+		if latest != "" {
+			return latest, nil
+		}
+		match = q.Matches
+	case QueryPatch, QueryMinor:
+		if current == "" || !semver.IsValid(current) {
+			// Like 'go get', without a current version these behave as latest.
+			return (&VersionQuery{Raw: q.Raw, Kind: QueryLatest}).Select("", latest, versions)
+		}
+		match = func(v string) bool {
+			if q.Kind == QueryPatch {
+				return semver.MajorMinor(v) == semver.MajorMinor(current)
+			}
+			return semver.Major(v) == semver.Major(current)
+		}
+	default:
+		match = q.Matches
+	}
+
+	var selected string
+	for _, v := range versions {
+		if semver.IsValid(v) && semver.Prerelease(v) == "" && match(v) && semver.Compare(v, selected) > 0 {
+			selected = v
+		}
+	}
+	if q.Kind == QueryPatch || q.Kind == QueryMinor {
+		// Never select a version older than the current one.
+		if selected == "" || semver.Compare(current, selected) > 0 {
+			selected = current
+		}
+	}
+	if selected == "" {
+		return "", fmt.Errorf("no version matches query %q", q.Raw)
+	}
+	return selected, nil
+}
@@ -0,0 +1,124 @@
+package types //nolint:revive // types is a valid package name
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVersionQuery(t *testing.T) {
+	testCases := []struct {
+		query     string
+		wantKind  QueryKind
+		matches   []string
+		unmatches []string
+		wantErr   string
+	}{{
+		query:    "latest",
+		wantKind: QueryLatest,
+	}, {
+		query:     "^v1.4",
+		wantKind:  QueryRange,
+		matches:   []string{"v1.4.0", "v1.9.3"},
+		unmatches: []string{"v1.3.9", "v2.0.0"},
+	}, {
+		query:     "^v0.9.1",
+		wantKind:  QueryRange,
+		matches:   []string{"v0.9.1", "v0.9.7"},
+		unmatches: []string{"v0.9.0", "v0.10.0"},
+	}, {
+		query:     "~v0.9.1",
+		wantKind:  QueryRange,
+		matches:   []string{"v0.9.1", "v0.9.12"},
+		unmatches: []string{"v0.10.0"},
+	}, {
+		query:     ">=v1.2.3,<v2",
+		wantKind:  QueryRange,
+		matches:   []string{"v1.2.3", "v1.99.0"},
+		unmatches: []string{"v1.2.2", "v2.0.0", "master"},
+	}, {
+		query:   "~latest",
+		wantErr: "not a valid semantic version",
+	}, {
+		query:   ">=v1.2.3,v2",
+		wantErr: "must start with one of",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			if !IsVersionQuery(tc.query) {
+				t.Fatalf("IsVersionQuery(%q) = false, want true", tc.query)
+			}
+			q, err := ParseVersionQuery(tc.query)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("ParseVersionQuery(%q) = %v, want error containing %q", tc.query, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseVersionQuery(%q) = %v", tc.query, err)
+			}
+			if q.Kind != tc.wantKind {
+				t.Errorf("ParseVersionQuery(%q).Kind = %q, want %q", tc.query, q.Kind, tc.wantKind)
+			}
+			for _, v := range tc.matches {
+				if !q.Matches(v) {
+					t.Errorf("%q does not match %s", tc.query, v)
+				}
+			}
+			for _, v := range tc.unmatches {
+				if q.Matches(v) {
+					t.Errorf("%q matches %s", tc.query, v)
+				}
+			}
+		})
+	}
+
+	for _, v := range []string{"v1.2.3", "v0.0.0-20230101000000-abcdef123456", "master"} {
+		if IsVersionQuery(v) {
+			t.Errorf("IsVersionQuery(%q) = true, want false", v)
+		}
+	}
+}
+
+func TestVersionQuerySelect(t *testing.T) {
+	versions := []string{"v1.0.0", "v1.2.3", "v1.2.5", "v1.3.0", "v1.4.0-rc.1", "v2.0.0+incompatible"}
+	testCases := []struct {
+		query   string
+		current string
+		latest  string
+		want    string
+		wantErr string
+	}{
+		{query: "latest", latest: "v1.3.0", want: "v1.3.0"},
+		{query: "latest", want: "v2.0.0+incompatible"},
+		{query: "patch", current: "v1.2.3", latest: "v1.3.0", want: "v1.2.5"},
+		{query: "patch", latest: "v1.3.0", want: "v1.3.0"},
+		{query: "patch", current: "v1.2.9", latest: "v1.3.0", want: "v1.2.9"},
+		{query: "minor", current: "v1.0.0", latest: "v1.3.0", want: "v1.3.0"},
+		{query: "^v1.2", want: "v1.3.0"},
+		{query: "~v1.2.0", want: "v1.2.5"},
+		{query: ">=v1.2.3,<v1.2.5", want: "v1.2.3"},
+		{query: ">v3", wantErr: "no version matches"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query+"/"+tc.current, func(t *testing.T) {
+			q, err := ParseVersionQuery(tc.query)
+			if err != nil {
+				t.Fatalf("ParseVersionQuery(%q) = %v", tc.query, err)
+			}
+			got, err := q.Select(tc.current, tc.latest, versions)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Select() = %q, %v, want error containing %q", got, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Select() = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Select() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
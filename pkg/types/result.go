@@ -0,0 +1,130 @@
+package types //nolint:revive
+
+import "golang.org/x/mod/modfile"
+
+// PackageKind tells whether a requested package was handled as a require or a replace.
+type PackageKind string
+
+const (
+	// KindRequire is a package bumped through its require directive.
+	KindRequire PackageKind = "require"
+	// KindReplace is a package bumped through a replace directive.
+	KindReplace PackageKind = "replace"
+)
+
+// SkipReason is a machine-readable reason for skipping a requested package.
+type SkipReason string
+
+const (
+	// SkipReasonDowngrade means the requested version is older than the one already in go.mod.
+	SkipReasonDowngrade SkipReason = "downgrade"
+	// SkipReasonDirectoryReplace means go.mod replaces the package with a local directory,
+	// so the build would ignore the requested version.
+	SkipReasonDirectoryReplace SkipReason = "directory-replace"
+)
+
+// GetMode tells how the requested requires were fetched with 'go get'.
+type GetMode string
+
+const (
+	// GetModeOrdered runs one 'go get' per package, in request order.
+	GetModeOrdered GetMode = "ordered"
+	// GetModeBatched runs a single 'go get' for all the packages.
+	GetModeBatched GetMode = "batched"
+	// GetModeOrderedFallback means the batched 'go get' failed and the ordered mode was used instead.
+	GetModeOrderedFallback GetMode = "ordered-fallback"
+)
+
+// Result describes the outcome of an update.
+type Result struct {
+	Modroot string `json:"modroot"`
+	DryRun  bool   `json:"dryRun,omitempty"`
+	// Changed tells whether go.mod was (or, on a dry run, would be) modified.
+	Changed bool `json:"changed"`
+	// Packages lists every requested package, in request order.
+	Packages []PackageResult `json:"packages"`
+	// Directives lists the requested changes to the go and toolchain lines.
+	Directives []DirectiveChange `json:"directives,omitempty"`
+	// Collateral lists required modules that moved without being requested.
+	Collateral []ModuleChange `json:"collateral,omitempty"`
+	// GetMode tells how the requires were fetched.
+	GetMode GetMode `json:"getMode,omitempty"`
+	// GoVersion is the go version the module was tidied with, and GoVersionSource where it comes from.
+	GoVersion       string          `json:"goVersion,omitempty"`
+	GoVersionSource GoVersionSource `json:"goVersionSource,omitempty"`
+	// Diff is the difference between the go.mod before and after the update.
+	Diff string `json:"diff,omitempty"`
+	// ModFile is the parsed go.mod after the update.
+	ModFile *modfile.File `json:"-"`
+}
+
+// PackageResult describes what happened to a single requested package.
+// Query is the version query RequestedVersion was resolved from, if any.
+// Transitive tells the package was only in the module graph, and was bumped with an indirect require.
+type PackageResult struct {
+	Name             string      `json:"name"`
+	OldName          string      `json:"oldName,omitempty"`
+	OldVersion       string      `json:"oldVersion,omitempty"`
+	Kind             PackageKind `json:"kind"`
+	RequestedVersion string      `json:"requestedVersion"`
+	Query            string      `json:"query,omitempty"`
+	Mode             VersionMode `json:"mode,omitempty"`
+	Before           string      `json:"before,omitempty"`
+	After            string      `json:"after,omitempty"`
+	Transitive       bool        `json:"transitive,omitempty"`
+	Skipped          bool        `json:"skipped,omitempty"`
+	SkipReason       SkipReason  `json:"skipReason,omitempty"`
+	Message          string      `json:"message,omitempty"`
+	Warnings         []string    `json:"warnings,omitempty"`
+}
+
+// DirectiveChange describes what happened to a requested go or toolchain line.
+type DirectiveChange struct {
+	// Directive is either "go" or "toolchain".
+	Directive string `json:"directive"`
+	Requested string `json:"requested"`
+	Before    string `json:"before,omitempty"`
+	After     string `json:"after,omitempty"`
+	Skipped   bool   `json:"skipped,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// ModuleChange describes a required module whose version changed.
+// An empty Before means the module was added, an empty After that it was removed.
+type ModuleChange struct {
+	Path   string `json:"path"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// WorkspaceResult describes the outcome of an update of every module of a go.work.
+type WorkspaceResult struct {
+	GoWork string `json:"goWork"`
+	DryRun bool   `json:"dryRun,omitempty"`
+	// Replaces lists the packages bumped through a replace directive of go.work.
+	Replaces []PackageResult `json:"replaces,omitempty"`
+	// Modules holds the result of every module used by go.work, in the order of the use directives.
+	Modules []*Result `json:"modules"`
+}
+
+// PruneResult describes the outcome of pruning the stale replaces of a module.
+type PruneResult struct {
+	Modroot string `json:"modroot"`
+	DryRun  bool   `json:"dryRun,omitempty"`
+	// Pruned lists the replaces that were (or, on a dry run, would be) dropped, in go.mod order.
+	Pruned []PrunedReplace `json:"pruned"`
+	// Diff is the difference between the go.mod before and after pruning.
+	Diff string `json:"diff,omitempty"`
+}
+
+// PrunedReplace is a replace of a module by another version of itself that no longer raises
+// the version required by go.mod.
+type PrunedReplace struct {
+	Path string `json:"path"`
+	// OldVersion is the version the replace applied to, empty when it applied to all of them.
+	OldVersion string `json:"oldVersion,omitempty"`
+	// Version is the version the replace pinned.
+	Version string `json:"version"`
+	// Required is the version go.mod requires.
+	Required string `json:"required"`
+}
@@ -1,30 +1,168 @@
 package types //nolint:revive
 
+import (
+	"fmt"
+	"go/version"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
+)
+
 // Package represents a Go module package to be updated or replaced.
 type Package struct {
 	OldName string `json:"oldName,omitempty" yaml:"oldName,omitempty"`
-	Name    string `json:"name,omitempty" yaml:"name,omitempty"`
-	Version string `json:"version,omitempty" yaml:"version,omitempty"`
-	Replace bool   `json:"replace,omitempty" yaml:"replace,omitempty"`
-	Require bool   `json:"require,omitempty" yaml:"require,omitempty"`
-	Index   int    `json:"index,omitempty" yaml:"index,omitempty"`
+	// OldVersion targets the replace of this version of OldName only, e.g. v1.2.3 for the
+	// 'old v1.2.3 => new v1.2.4' replace. Defaults to the version of the replace found in go.mod.
+	OldVersion string `json:"oldVersion,omitempty" yaml:"oldVersion,omitempty"`
+	Name       string `json:"name,omitempty" yaml:"name,omitempty"`
+	Version    string `json:"version,omitempty" yaml:"version,omitempty"`
+	Replace    bool   `json:"replace,omitempty" yaml:"replace,omitempty"`
+	Require    bool   `json:"require,omitempty" yaml:"require,omitempty"`
+	Index      int    `json:"index,omitempty" yaml:"index,omitempty"`
 	// Force allows downgrading a package to a version older than the current one.
 	// By default, downgrade attempts are skipped with a warning.
 	Force bool `json:"force,omitempty" yaml:"force,omitempty"`
+	// Mode selects how Version is interpreted, defaults to VersionModeMinimum.
+	Mode VersionMode `json:"mode,omitempty" yaml:"mode,omitempty"`
+	// Reason tells why the package is bumped, e.g. CVE-2025-1234. With Config.Annotate it is
+	// written as a '// gobump: <reason>' comment on the require or replace line of go.mod.
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// OldModule returns the left-hand side of the replace of the package, OldName or OldName@OldVersion.
+func (p *Package) OldModule() string {
+	if p.OldVersion == "" {
+		return p.OldName
+	}
+	return p.OldName + "@" + p.OldVersion
+}
+
+// AllowsDowngrade tells whether the package may be moved to a version older than the current one.
+func (p *Package) AllowsDowngrade() bool {
+	return p.Force || p.Mode == VersionModeExact
+}
+
+// VersionMode selects how the version of a package is interpreted.
+type VersionMode string
+
+const (
+	// VersionModeMinimum requires at least the requested version, packages already at a newer
+	// version are left alone. This is the default.
+	VersionModeMinimum VersionMode = "minimum"
+	// VersionModeExact pins exactly the requested version, downgrading the package if needed.
+	VersionModeExact VersionMode = "exact"
+	// VersionModeLatestPatchAbove takes the latest patch release in the minor line of the
+	// requested version, and at least the requested version.
+	VersionModeLatestPatchAbove VersionMode = "latest-patch-above"
+)
+
+// GoVersionSource is where the go version passed to 'go mod tidy -go' and set in go.work comes from.
+type GoVersionSource string
+
+const (
+	// GoVersionSourceFlag is the version given in Config.GoVersion.
+	GoVersionSourceFlag GoVersionSource = "flag"
+	// GoVersionSourceEnv is the version of the go command, from 'go env GOVERSION'.
+	GoVersionSourceEnv GoVersionSource = "env"
+	// GoVersionSourceGoMod is the go line of go.mod.
+	GoVersionSourceGoMod GoVersionSource = "gomod"
+	// GoVersionSourceGoWork is the go line of the go.work of the module.
+	GoVersionSourceGoWork GoVersionSource = "gowork"
+	// GoVersionSourceToolchain is the toolchain line of go.mod.
+	GoVersionSourceToolchain GoVersionSource = "toolchain"
+)
+
+// DefaultGoVersionSources are the sources tried when Config.GoVersionSources is empty.
+var DefaultGoVersionSources = []GoVersionSource{GoVersionSourceFlag, GoVersionSourceEnv}
+
+// EditBackend selects how gobump applies edits to go.mod.
+type EditBackend string
+
+const (
+	// EditBackendGo shells out to 'go mod edit' once per edit. This is the default.
+	EditBackendGo EditBackend = "go"
+	// EditBackendModfile applies every edit in memory and writes go.mod once.
+	// Only 'go get' and 'go mod tidy' are left to the go command.
+	EditBackendModfile EditBackend = "modfile"
+)
+
+// Timeouts bounds the duration of each go command run during an update.
+// A zero value means no limit for that step.
+type Timeouts struct {
+	// Tidy bounds every 'go mod tidy'.
+	Tidy time.Duration
+	// Edit bounds every 'go mod edit' and 'go work edit'.
+	Edit time.Duration
+	// Get bounds every 'go get'.
+	Get time.Duration
+	// Vendor bounds 'go mod vendor' and 'go work vendor'.
+	Vendor time.Duration
 }
 
 // Config contains configuration options for the update process.
 type Config struct {
-	Modroot         string
-	GoVersion       string
-	ShowDiff        bool
-	Tidy            bool
-	TidyCompat      string
-	TidySkipInitial bool
-	ForceWork       bool
+	Modroot   string
+	GoVersion string
+	// GoVersionSources are tried in order until one yields the go version to tidy with,
+	// defaults to DefaultGoVersionSources. GoVersionSourceGoMod keeps the go line of go.mod as is.
+	GoVersionSources []GoVersionSource
+	ShowDiff         bool
+	Tidy             bool
+	TidyCompat       string
+	TidySkipInitial  bool
+	ForceWork        bool
+	// DryRun runs the update against a scratch copy of the modroot and
+	// reports the resulting changes without touching the original module.
+	DryRun bool
+	// Transactional restores go.mod, go.sum, go.work, go.work.sum and
+	// vendor/modules.txt to their original content if the update fails.
+	Transactional bool
+	// EditBackend selects how go.mod edits are applied, defaults to EditBackendGo.
+	EditBackend EditBackend
+	// Runner runs the go commands of the update, defaults to run.ExecRunner.
+	Runner run.Runner
+	// Timeouts bounds the individual go commands of the update.
+	Timeouts Timeouts
+	// Directives are the go and toolchain lines to set in go.mod, and in go.work if any.
+	Directives Directives
+	// MaxGoVersion, e.g. 1.22 or 1.22.5, makes the update fail when it raises the go or toolchain
+	// line of go.mod above it, as 'go get' does for dependencies requiring a newer go.
+	MaxGoVersion string
+	// Modroots lists the modules to update with update.DoUpdateModules, which defaults to Modroot.
+	Modroots []string
+	// BatchGet fetches all the requires with a single 'go get' instead of one per package,
+	// falling back to one per package if the batched call fails.
+	BatchGet bool
+	// Annotate writes the Reason of every bumped package as a trailing comment of its require,
+	// or replace, line of go.mod, updating the comment left there by a previous run.
+	Annotate bool
 }
 
 // PackageList is used to marshal from yaml/json file to get the list of packages.
 type PackageList struct {
 	Packages []Package `json:"packages" yaml:"packages"`
+	Directives
+}
+
+// Directives are the go and toolchain lines to set in go.mod along with the packages.
+// By default they are only raised, go.mod is left alone when it already has newer ones.
+type Directives struct {
+	// Go is the version of the go directive, e.g. 1.22.5.
+	Go string `json:"goDirective,omitempty" yaml:"goDirective,omitempty"`
+	// Toolchain is the name of the toolchain, e.g. go1.22.5.
+	Toolchain string `json:"toolchain,omitempty" yaml:"toolchain,omitempty"`
+	// Force allows lowering the go and toolchain lines.
+	Force bool `json:"forceDirectives,omitempty" yaml:"forceDirectives,omitempty"`
+}
+
+// Validate checks that the requested go version and toolchain name are well formed.
+func (d Directives) Validate() error {
+	if d.Go != "" && (strings.HasPrefix(d.Go, "go") || !version.IsValid("go"+d.Go)) {
+		return fmt.Errorf("invalid go directive %q, it should look like 1.22.5", d.Go)
+	}
+	if d.Toolchain != "" && !version.IsValid(d.Toolchain) {
+		return fmt.Errorf("invalid toolchain %q, it should look like go1.22.5", d.Toolchain)
+	}
+	return nil
 }
@@ -0,0 +1,83 @@
+package update
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// annotationPrefix starts the part of a trailing go.mod comment that gobump owns, which always comes
+// last: '// gobump: <reason>', or '// indirect; gobump: <reason>' for an indirect require.
+const annotationPrefix = "gobump:"
+
+// annotate writes the reason of every package of pkgVersions that has one as the trailing comment of
+// its replace, or of its require, in the go.mod at modpath. The comments already on the line are
+// kept, except for the annotation of a previous run which is replaced.
+func annotate(modpath string, pkgVersions map[string]*types.Package) error {
+	content, err := os.ReadFile(filepath.Clean(modpath))
+	if err != nil {
+		return err
+	}
+	modFile, err := modfile.Parse(filepath.Base(modpath), content, nil)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, k := range orderPkgVersionsMap(pkgVersions) {
+		pkg := pkgVersions[k]
+		reason := strings.Join(strings.Fields(pkg.Reason), " ")
+		if reason == "" {
+			continue
+		}
+		if pkg.Replace {
+			for _, r := range modFile.Replace {
+				if r.Old.Path == pkg.OldName && r.New.Path == pkg.Name && (pkg.OldVersion == "" || r.Old.Version == pkg.OldVersion) {
+					changed = annotateLine(r.Syntax, reason) || changed
+				}
+			}
+			continue
+		}
+		for _, r := range modFile.Require {
+			if r.Mod.Path == pkg.Name {
+				changed = annotateLine(r.Syntax, reason) || changed
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := writeFormatted(modpath, modFile.Format); err != nil {
+		return fmt.Errorf("failed to write the go mod file: %w", err)
+	}
+	return nil
+}
+
+// annotateLine sets the annotation of line to reason, after the comment already there if any.
+// It tells whether the line changed.
+func annotateLine(line *modfile.Line, reason string) bool {
+	var text string
+	if len(line.Suffix) > 0 {
+		text = strings.TrimSpace(strings.TrimPrefix(line.Suffix[0].Token, "//"))
+	}
+	if i := strings.Index(text, annotationPrefix); i >= 0 {
+		text = strings.TrimRight(text[:i], "; ")
+	}
+	token := "// " + annotationPrefix + " " + reason
+	if text != "" {
+		token = "// " + text + "; " + annotationPrefix + " " + reason
+	}
+	if len(line.Suffix) > 0 {
+		if line.Suffix[0].Token == token {
+			return false
+		}
+		line.Suffix[0].Token = token
+		return true
+	}
+	line.Suffix = []modfile.Comment{{Token: token, Suffix: true}}
+	return true
+}
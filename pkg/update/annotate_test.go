@@ -0,0 +1,92 @@
+package update
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+func TestAnnotate(t *testing.T) {
+	const gomod = `module example.com/app
+
+go 1.22
+
+require (
+	example.com/foo v1.2.5
+	example.com/pinned v1.0.0 // pinned for the v1 API
+	example.com/seen v1.1.0 // gobump: GO-2024-0001
+	example.com/untouched v1.0.0 // gobump: GO-2024-0009
+)
+
+require example.com/bar v1.1.0 // indirect
+
+replace example.com/old v1.0.0 => example.com/new v1.2.0
+`
+	const want = `module example.com/app
+
+go 1.22
+
+require (
+	example.com/foo v1.2.5 // gobump: CVE-2025-1234
+	example.com/pinned v1.0.0 // pinned for the v1 API; gobump: GO-2024-0003
+	example.com/seen v1.1.0 // gobump: GO-2024-0001, GO-2024-0002
+	example.com/untouched v1.0.0 // gobump: GO-2024-0009
+)
+
+require example.com/bar v1.1.0 // indirect; gobump: fixes the parser
+
+replace example.com/old v1.0.0 => example.com/new v1.2.0 // gobump: GO-2024-0005
+`
+	pkgVersions := map[string]*types.Package{
+		"example.com/foo":    {Name: "example.com/foo", Version: "v1.2.5", Require: true, Reason: "CVE-2025-1234"},
+		"example.com/pinned": {Name: "example.com/pinned", Version: "v1.0.0", Require: true, Reason: "GO-2024-0003"},
+		"example.com/seen":   {Name: "example.com/seen", Version: "v1.1.0", Require: true, Reason: "GO-2024-0001, GO-2024-0002"},
+		"example.com/bar":    {Name: "example.com/bar", Version: "v1.1.0", Reason: "fixes\nthe parser"},
+		"example.com/new":    {OldName: "example.com/old", Name: "example.com/new", Version: "v1.2.0", Replace: true, Reason: "GO-2024-0005"},
+	}
+	modpath := filepath.Join(t.TempDir(), "go.mod")
+	if err := os.WriteFile(modpath, []byte(gomod), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// The second run finds its own annotations and leaves them alone.
+	for range 2 {
+		if err := annotate(modpath, pkgVersions); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(modpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("go.mod (-want +got)\n%s", diff)
+		}
+	}
+
+	modFile, _, err := ParseGoModfile(modpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range modFile.Require {
+		if req.Mod.Path == "example.com/bar" && !req.Indirect {
+			t.Errorf("example.com/bar is no longer an indirect require")
+		}
+	}
+}
+
+func TestAnnotateUpdate(t *testing.T) {
+	runner, modroot := fooProxyRunner(t)
+	pkgVersions := map[string]*types.Package{
+		"example.com/foo": {Name: "example.com/foo", Version: "v1.2.5", Reason: "CVE-2025-1234"},
+	}
+	result, err := DoUpdateWithResult(pkgVersions, &types.Config{Modroot: modroot, Runner: runner, Annotate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.ModFile.Require[0].Syntax.Suffix; len(got) != 1 || got[0].Token != "// gobump: CVE-2025-1234" {
+		t.Errorf("require of example.com/foo has comments %+v, want the gobump annotation", got)
+	}
+}
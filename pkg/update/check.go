@@ -0,0 +1,65 @@
+package update
+
+import (
+	"fmt"
+	"path"
+
+	"golang.org/x/mod/modfile"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// Check verifies, without editing anything, that the go.mod in modroot already satisfies every
+// requested package. It returns the unsatisfied packages, in request order, with the reason in Message.
+// Check doesn't reach the module proxy: a range query is satisfied by a version matching the range,
+// while the latest, patch and minor queries only require the package to be present.
+func Check(pkgVersions map[string]*types.Package, modroot string) ([]types.PackageResult, error) {
+	modFile, _, err := ParseGoModfile(path.Join(modroot, "go.mod"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the go mod file with error: %w", err)
+	}
+
+	replaced := make(map[string]bool, len(modFile.Replace))
+	for _, replace := range modFile.Replace {
+		replaced[replace.New.Path] = true
+	}
+
+	var unsatisfied []types.PackageResult
+	for _, k := range orderPkgVersionsMap(pkgVersions) {
+		pkg := pkgVersions[k]
+		if err := checkPackage(modFile, pkg); err != nil {
+			kind := types.KindRequire
+			if pkg.Replace || replaced[pkg.Name] {
+				kind = types.KindReplace
+			}
+			unsatisfied = append(unsatisfied, types.PackageResult{
+				Name:             pkg.Name,
+				OldName:          pkg.OldName,
+				Kind:             kind,
+				RequestedVersion: pkg.Version,
+				Before:           getVersion(modFile, pkg.Name),
+				Message:          err.Error(),
+			})
+		}
+	}
+	return unsatisfied, nil
+}
+
+// checkPackage is verifyPackage, also handling version queries.
+func checkPackage(modFile *modfile.File, pkg *types.Package) error {
+	if !types.IsVersionQuery(pkg.Version) {
+		return verifyPackage(modFile, pkg)
+	}
+	q, err := types.ParseVersionQuery(pkg.Version)
+	if err != nil {
+		return err
+	}
+	verStr := getVersion(modFile, pkg.Name)
+	if verStr == "" {
+		return &NotFoundError{Package: pkg.Name}
+	}
+	if !q.Matches(verStr) {
+		return &QueryMismatchError{Package: pkg.Name, Version: verStr, Query: pkg.Version}
+	}
+	return nil
+}
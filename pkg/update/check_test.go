@@ -0,0 +1,83 @@
+package update
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+func TestCheck(t *testing.T) {
+	testCases := []struct {
+		name        string
+		pkgVersions map[string]*types.Package
+		want        []types.PackageResult
+	}{
+		{
+			name: "satisfied require and replace",
+			pkgVersions: map[string]*types.Package{
+				"github.com/google/uuid": {Name: "github.com/google/uuid", Version: "v1.3.0", Index: 0},
+				"k8s.io/api":             {Name: "k8s.io/api", Version: "v0.26.10", Index: 1},
+			},
+		},
+		{
+			name: "require below requested version",
+			pkgVersions: map[string]*types.Package{
+				"github.com/google/uuid": {Name: "github.com/google/uuid", Version: "v1.4.0"},
+			},
+			want: []types.PackageResult{{
+				Name:             "github.com/google/uuid",
+				Kind:             types.KindRequire,
+				RequestedVersion: "v1.4.0",
+				Before:           "v1.3.1",
+				Message:          "package github.com/google/uuid with v1.3.1 is less than the desired version v1.4.0",
+			}},
+		},
+		{
+			name: "replace below requested version and missing package",
+			pkgVersions: map[string]*types.Package{
+				"k8s.io/client-go":   {Name: "k8s.io/client-go", Version: "v0.28.0", Index: 0},
+				"example.com/absent": {Name: "example.com/absent", Version: "v1.0.0", Index: 1},
+			},
+			want: []types.PackageResult{{
+				Name:             "k8s.io/client-go",
+				Kind:             types.KindReplace,
+				RequestedVersion: "v0.28.0",
+				Before:           "v0.26.10",
+				Message:          "package k8s.io/client-go with v0.26.10 is less than the desired version v0.28.0",
+			}, {
+				Name:             "example.com/absent",
+				Kind:             types.KindRequire,
+				RequestedVersion: "v1.0.0",
+				Message:          "package example.com/absent was not found on the go.mod file. Please remove the package or add it to the list of 'replaces'",
+			}},
+		},
+		{
+			name: "version queries",
+			pkgVersions: map[string]*types.Package{
+				"github.com/google/uuid": {Name: "github.com/google/uuid", Version: "~v1.4.0", Index: 0},
+				"k8s.io/api":             {Name: "k8s.io/api", Version: "latest", Index: 1},
+			},
+			want: []types.PackageResult{{
+				Name:             "github.com/google/uuid",
+				Kind:             types.KindRequire,
+				RequestedVersion: "~v1.4.0",
+				Before:           "v1.3.1",
+				Message:          `package github.com/google/uuid: version v1.3.1 does not match query "~v1.4.0"`,
+			}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Check(tc.pkgVersions, "testdata/aws-efs-csi-driver")
+			if err != nil {
+				t.Fatalf("Check() = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Check() unsatisfied (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"go/version"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// applyDirectives sets the go and toolchain lines of the go.mod at modpath to the requested ones,
+// refusing to lower them unless forced. The go.work at workPath, if any, is raised to match so
+// the workspace keeps accepting the module.
+func applyDirectives(modpath, workPath string, d types.Directives) ([]types.DirectiveChange, error) {
+	if d.Go == "" && d.Toolchain == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(filepath.Clean(modpath))
+	if err != nil {
+		return nil, err
+	}
+	modFile, err := modfile.Parse(filepath.Base(modpath), content, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []types.DirectiveChange
+	if d.Go != "" {
+		var before string
+		if modFile.Go != nil {
+			before = modFile.Go.Version
+		}
+		change := directiveChange("go", d.Go, before, "go"+d.Go, "go"+before, d.Force)
+		if change.After != before {
+			if err := modFile.AddGoStmt(d.Go); err != nil {
+				return nil, fmt.Errorf("setting the go directive to %s: %w", d.Go, err)
+			}
+		}
+		changes = append(changes, change)
+	}
+	if d.Toolchain != "" {
+		var before string
+		if modFile.Toolchain != nil {
+			before = modFile.Toolchain.Name
+		}
+		change := directiveChange("toolchain", d.Toolchain, before, d.Toolchain, before, d.Force)
+		if change.After != before {
+			if err := modFile.AddToolchainStmt(d.Toolchain); err != nil {
+				return nil, fmt.Errorf("setting the toolchain to %s: %w", d.Toolchain, err)
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	changed := false
+	for _, c := range changes {
+		changed = changed || c.After != c.Before
+	}
+	if !changed {
+		return changes, nil
+	}
+	modFile.Cleanup()
+	if err := writeFormatted(modpath, modFile.Format); err != nil {
+		return nil, err
+	}
+	if workPath != "" {
+		if err := raiseWorkDirectives(workPath, modFile); err != nil {
+			return nil, fmt.Errorf("failed to sync the go work file: %w", err)
+		}
+	}
+	return changes, nil
+}
+
+// directiveChange decides the outcome of requesting the requested value of a directive whose
+// current value is before. cmpRequested and cmpBefore are the go toolchain names to compare.
+func directiveChange(directive, requested, before, cmpRequested, cmpBefore string, force bool) types.DirectiveChange {
+	change := types.DirectiveChange{Directive: directive, Requested: requested, Before: before, After: requested}
+	if before != "" && version.Compare(cmpRequested, cmpBefore) < 0 && !force {
+		change.After = before
+		change.Skipped = true
+		change.Message = fmt.Sprintf("requested %s %q is lower than current %q", directive, requested, before)
+		log.Printf("Warning: %s, skipping", change.Message)
+	}
+	return change
+}
+
+// raiseWorkDirectives raises the go and toolchain lines of the go.work at workPath to the ones of modFile.
+func raiseWorkDirectives(workPath string, modFile *modfile.File) error {
+	workFile, err := ParseGoWorkfile(workPath)
+	if err != nil {
+		return err
+	}
+	changed := false
+	if modFile.Go != nil && (workFile.Go == nil || version.Compare("go"+workFile.Go.Version, "go"+modFile.Go.Version) < 0) {
+		log.Printf("Raising the go.work go directive to %s ...\n", modFile.Go.Version)
+		if err := workFile.AddGoStmt(modFile.Go.Version); err != nil {
+			return err
+		}
+		changed = true
+	}
+	// In workspace mode the toolchain line of go.work wins over the ones of the modules.
+	if modFile.Toolchain != nil && (workFile.Toolchain == nil || version.Compare(workFile.Toolchain.Name, modFile.Toolchain.Name) < 0) {
+		log.Printf("Raising the go.work toolchain to %s ...\n", modFile.Toolchain.Name)
+		if err := workFile.AddToolchainStmt(modFile.Toolchain.Name); err != nil {
+			return err
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	workFile.Cleanup()
+	return writeFormatted(workPath, func() ([]byte, error) {
+		return modfile.Format(workFile.Syntax), nil
+	})
+}
+
+// writeFormatted writes the output of format to path, keeping its permissions.
+func writeFormatted(path string, format func() ([]byte, error)) error {
+	out, err := format()
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, info.Mode().Perm())
+}
+
+// exceedsGoVersion tells whether the go version of the toolchain name v, e.g. go1.22.5, is above the
+// go version limit, e.g. 1.22.5. A language version such as 1.22 as limit allows all of its releases.
+func exceedsGoVersion(v, limit string) bool {
+	if version.Lang("go"+limit) == "go"+limit {
+		return version.Compare(version.Lang(v), "go"+limit) > 0
+	}
+	return version.Compare(v, "go"+limit) > 0
+}
+
+// validateMaxGoVersion checks that limit is a go version the requested directives don't exceed.
+func validateMaxGoVersion(limit string, d types.Directives) error {
+	if strings.HasPrefix(limit, "go") || !version.IsValid("go"+limit) {
+		return fmt.Errorf("invalid maximum go version %q, it should look like 1.22 or 1.22.5", limit)
+	}
+	if d.Go != "" && exceedsGoVersion("go"+d.Go, limit) {
+		return fmt.Errorf("requested go directive %q is above the maximum go version %s", d.Go, limit)
+	}
+	if d.Toolchain != "" && exceedsGoVersion(d.Toolchain, limit) {
+		return fmt.Errorf("requested toolchain %q is above the maximum go version %s", d.Toolchain, limit)
+	}
+	return nil
+}
+
+// validateTidyGoVersion checks that tidying with the go version goVersion doesn't raise the go
+// line of the original go.mod above the go version limit.
+func validateTidyGoVersion(limit, goVersion string, before *modfile.File) error {
+	if goVersion == "" {
+		return nil
+	}
+	var beforeGo string
+	if before.Go != nil {
+		beforeGo = "go" + before.Go.Version
+	}
+	if raisedAbove(beforeGo, "go"+goVersion, limit) {
+		return fmt.Errorf("go version %q for go mod tidy is above the maximum go version %s", goVersion, limit)
+	}
+	return nil
+}
+
+// checkMaxGoVersion returns a *GoVersionError if the update raised the go or toolchain line of go.mod,
+// from before to after, above the go version limit. The go.mod of every module in bumped, module
+// path to version, is looked up to name the ones requiring the higher go version.
+func checkMaxGoVersion(ctx context.Context, runner run.Runner, modroot, limit string, before, after *modfile.File, bumped map[string]string) error {
+	lines := func(f *modfile.File) (goLine, toolchain string) {
+		if f.Go != nil {
+			goLine = "go" + f.Go.Version
+		}
+		if f.Toolchain != nil {
+			toolchain = f.Toolchain.Name
+		}
+		return goLine, toolchain
+	}
+	beforeGo, beforeToolchain := lines(before)
+	afterGo, afterToolchain := lines(after)
+
+	var gerr *GoVersionError
+	switch {
+	case raisedAbove(beforeGo, afterGo, limit):
+		gerr = &GoVersionError{Directive: "go", Before: before.Go.Version, After: after.Go.Version, Max: limit}
+	case raisedAbove(beforeToolchain, afterToolchain, limit):
+		gerr = &GoVersionError{Directive: "toolchain", Before: beforeToolchain, After: afterToolchain, Max: limit}
+	default:
+		return nil
+	}
+
+	paths := make([]string, 0, len(bumped))
+	for p := range bumped {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		info, err := run.GoListModule(ctx, runner, modroot, p+"@"+bumped[p], false)
+		if err != nil {
+			log.Printf("Warning: unable to look up the go version required by %s@%s: %v", p, bumped[p], err)
+			continue
+		}
+		if info.GoVersion != "" && exceedsGoVersion("go"+info.GoVersion, limit) {
+			gerr.Culprits = append(gerr.Culprits, fmt.Sprintf("%s@%s (go %s)", p, bumped[p], info.GoVersion))
+		}
+	}
+	return gerr
+}
+
+// raisedAbove tells whether the toolchain name after is newer than before and above limit.
+func raisedAbove(before, after, limit string) bool {
+	return after != "" && (before == "" || version.Compare(after, before) > 0) && exceedsGoVersion(after, limit)
+}
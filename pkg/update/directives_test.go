@@ -0,0 +1,165 @@
+package update
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+func TestApplyDirectives(t *testing.T) {
+	testCases := []struct {
+		name          string
+		directives    types.Directives
+		want          []types.DirectiveChange
+		wantGo        string
+		wantToolchain string
+		wantWorkGo    string
+	}{
+		{
+			name:          "raise",
+			directives:    types.Directives{Go: "1.22.5", Toolchain: "go1.22.6"},
+			wantGo:        "1.22.5",
+			wantToolchain: "go1.22.6",
+			wantWorkGo:    "1.22.5",
+			want: []types.DirectiveChange{
+				{Directive: "go", Requested: "1.22.5", Before: "1.21", After: "1.22.5"},
+				{Directive: "toolchain", Requested: "go1.22.6", Before: "go1.21.5", After: "go1.22.6"},
+			},
+		},
+		{
+			name:          "refuse to lower",
+			directives:    types.Directives{Go: "1.20"},
+			wantGo:        "1.21",
+			wantToolchain: "go1.21.5",
+			wantWorkGo:    "1.21",
+			want: []types.DirectiveChange{{
+				Directive: "go",
+				Requested: "1.20",
+				Before:    "1.21",
+				After:     "1.21",
+				Skipped:   true,
+				Message:   `requested go "1.20" is lower than current "1.21"`,
+			}},
+		},
+		{
+			name:          "forced lower",
+			directives:    types.Directives{Go: "1.20", Force: true},
+			wantGo:        "1.20",
+			wantToolchain: "go1.21.5",
+			wantWorkGo:    "1.21",
+			want:          []types.DirectiveChange{{Directive: "go", Requested: "1.20", Before: "1.21", After: "1.20"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			modpath := filepath.Join(dir, "go.mod")
+			workPath := filepath.Join(dir, "go.work")
+			if err := os.WriteFile(modpath, []byte("module example.com/app\n\ngo 1.21\n\ntoolchain go1.21.5\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(workPath, []byte("go 1.21\n\nuse .\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := applyDirectives(modpath, workPath, tc.directives)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("applyDirectives() (-want +got)\n%s", diff)
+			}
+
+			modFile, _, err := ParseGoModfile(modpath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if modFile.Go.Version != tc.wantGo || modFile.Toolchain.Name != tc.wantToolchain {
+				t.Errorf("go.mod has go %s and toolchain %s, want %s and %s", modFile.Go.Version, modFile.Toolchain.Name, tc.wantGo, tc.wantToolchain)
+			}
+			workFile, err := ParseGoWorkfile(workPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if workFile.Go.Version != tc.wantWorkGo {
+				t.Errorf("go.work has go %s, want %s", workFile.Go.Version, tc.wantWorkGo)
+			}
+		})
+	}
+}
+
+func TestExceedsGoVersion(t *testing.T) {
+	testCases := []struct {
+		v     string
+		limit string
+		want  bool
+	}{
+		{v: "go1.22.5", limit: "1.22", want: false},
+		{v: "go1.22", limit: "1.22", want: false},
+		{v: "go1.23.0", limit: "1.22", want: true},
+		{v: "go1.22.5", limit: "1.22.4", want: true},
+		{v: "go1.22.4", limit: "1.22.4", want: false},
+		{v: "go1.21", limit: "1.22.4", want: false},
+	}
+	for _, tc := range testCases {
+		if got := exceedsGoVersion(tc.v, tc.limit); got != tc.want {
+			t.Errorf("exceedsGoVersion(%q, %q) = %v, want %v", tc.v, tc.limit, got, tc.want)
+		}
+	}
+}
+
+func TestMaxGoVersion(t *testing.T) {
+	testCases := []struct {
+		name       string
+		limit      string
+		directives types.Directives
+		tidy       bool
+		goVersion  string
+		wantErr    string
+	}{
+		{name: "within the limit", limit: "1.23"},
+		{name: "above the limit", limit: "1.22", wantErr: `the update raised the go line from "1.21" to "1.23.0", above the maximum go version 1.22, required by example.com/foo@v1.3.0 (go 1.23.0)`},
+		{name: "requested directive above the limit", limit: "1.22", directives: types.Directives{Go: "1.23"}, wantErr: `requested go directive "1.23" is above the maximum go version 1.22`},
+		{name: "tidy go version above the limit", limit: "1.22", tidy: true, goVersion: "1.23", wantErr: `go version "1.23" for go mod tidy is above the maximum go version 1.22`},
+		{name: "invalid limit", limit: "go1.22", wantErr: `invalid maximum go version "go1.22"`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := tempProxy(t)
+			writeProxyGo(t, proxy, "example.com/foo", "1.21", "v1.2.3")
+			writeProxyGo(t, proxy, "example.com/foo", "1.23.0", "v1.3.0")
+			runner := proxyRunner(t, proxy, "GOTOOLCHAIN=local")
+			modroot := t.TempDir()
+			gomod := "module example.com/app\n\ngo 1.21\n\nrequire example.com/foo v1.2.3\n"
+			if err := os.WriteFile(filepath.Join(modroot, "go.mod"), []byte(gomod), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			pkgVersions := map[string]*types.Package{
+				"example.com/foo": {Name: "example.com/foo", Version: "v1.3.0"},
+			}
+			_, err := DoUpdateWithResult(pkgVersions, &types.Config{
+				Modroot:      modroot,
+				Runner:       runner,
+				MaxGoVersion: tc.limit,
+				Directives:   tc.directives,
+				Tidy:         tc.tidy,
+				GoVersion:    tc.goVersion,
+			})
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("DoUpdateWithResult() = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
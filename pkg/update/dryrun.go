@@ -0,0 +1,347 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// doDryRun runs the update pipeline against a scratch copy of the modroot and
+// reports the changes it would make. The original module is never modified.
+func doDryRun(ctx context.Context, pkgVersions map[string]*types.Package, cfg *types.Config) (*types.Result, error) {
+	scratchCfg, dirReplaces, cleanup, err := scratchModule(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	result, err := doUpdate(ctx, pkgVersions, scratchCfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range result.ModFile.Replace {
+		if dir, ok := dirReplaces[r.New.Path]; ok && r.New.Version == "" {
+			r.New.Path = dir
+		}
+	}
+	if result.Diff == "" {
+		log.Println("Dry run: go.mod would not change")
+	}
+	result.Modroot = cfg.Modroot
+	result.DryRun = true
+
+	return result, nil
+}
+
+// doPruneDryRun runs the prune against a scratch copy of the modroot and reports the replaces
+// it would drop. The original module is never modified.
+func doPruneDryRun(ctx context.Context, cfg *types.Config) (*types.PruneResult, error) {
+	scratchCfg, _, cleanup, err := scratchModule(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	result, err := pruneReplaces(ctx, scratchCfg)
+	if err != nil {
+		return nil, err
+	}
+	result.Modroot = cfg.Modroot
+	result.DryRun = true
+	return result, nil
+}
+
+// scratchModule copies the modroot of cfg to a scratch directory and returns a copy of cfg
+// that works on it, along with the original directories of the replaces made absolute and
+// a function removing the copy.
+func scratchModule(cfg *types.Config) (*types.Config, map[string]string, func(), error) {
+	modroot := cfg.Modroot
+	if modroot == "" {
+		modroot = "."
+	}
+
+	scratch, cleanup, err := scratchCopy(modroot)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// The directory replaces are relative to the modroot, not to the copy.
+	dirReplaces, err := absDirReplaces(filepath.Join(scratch, "go.mod"), modroot)
+	if err != nil {
+		cleanup()
+		return nil, nil, nil, fmt.Errorf("failed to rewrite the directory replaces for the dry run: %w", err)
+	}
+
+	scratchCfg := *cfg
+	scratchCfg.Modroot = scratch
+	scratchCfg.DryRun = false
+	// A go.work outside of the modroot is not part of the copy, and we must not let the
+	// pipeline go looking for (and editing) one elsewhere, even through GOWORK.
+	gowork := "off"
+	if _, err := os.Stat(filepath.Join(scratch, "go.work")); err == nil {
+		gowork = filepath.Join(scratch, "go.work")
+	} else {
+		scratchCfg.ForceWork = false
+	}
+	scratchCfg.Runner = withGoWork(cfg.Runner, gowork)
+	return &scratchCfg, dirReplaces, cleanup, nil
+}
+
+// doWorkspaceDryRun runs DoUpdateWorkspace against a scratch copy of the directory of the go.work
+// at workPath, and reports the changes it would make. The original workspace is never modified.
+// Every module of the workspace must be under that directory.
+func doWorkspaceDryRun(ctx context.Context, pkgVersions map[string]*types.Package, cfg *types.Config, workPath string, workFile *modfile.WorkFile) (*types.WorkspaceResult, error) {
+	workdir := filepath.Dir(workPath)
+	modroot, err := relativeTo(workdir, cfg.Modroot)
+	if err != nil {
+		return nil, err
+	}
+	modroots := make([]string, 0, len(workFile.Use))
+	for _, use := range workFile.Use {
+		if filepath.IsAbs(use.Path) {
+			return nil, fmt.Errorf("a dry run can't copy the workspace module %s, use a path relative to %s", use.Path, workPath)
+		}
+		rel, err := relativeTo(workdir, filepath.Join(workdir, use.Path))
+		if err != nil {
+			return nil, err
+		}
+		modroots = append(modroots, rel)
+	}
+
+	scratch, cleanup, err := scratchCopy(workdir)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// The directory replaces are relative to the original modules and workspace, not to the copy.
+	dirReplaces := map[string]string{}
+	for _, rel := range modroots {
+		dirs, err := absDirReplaces(filepath.Join(scratch, rel, "go.mod"), filepath.Join(workdir, rel))
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewrite the directory replaces for the dry run: %w", err)
+		}
+		maps.Copy(dirReplaces, dirs)
+	}
+	if err := absWorkDirReplaces(filepath.Join(scratch, "go.work"), workdir); err != nil {
+		return nil, fmt.Errorf("failed to rewrite the directory replaces of go.work for the dry run: %w", err)
+	}
+
+	scratchCfg := *cfg
+	scratchCfg.Modroot = filepath.Join(scratch, modroot)
+	scratchCfg.DryRun = false
+	scratchCfg.Runner = withGoWork(cfg.Runner, filepath.Join(scratch, "go.work"))
+	result, err := DoUpdateWorkspace(ctx, pkgVersions, &scratchCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result.GoWork = workPath
+	result.DryRun = true
+	for i, m := range result.Modules {
+		m.Modroot = filepath.Join(workdir, modroots[i])
+		m.DryRun = true
+		for _, r := range m.ModFile.Replace {
+			if dir, ok := dirReplaces[r.New.Path]; ok && r.New.Version == "" {
+				r.New.Path = dir
+			}
+		}
+	}
+	return result, nil
+}
+
+// relativeTo returns the path of dir relative to root, failing if dir is not under root.
+func relativeTo(root, dir string) (string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absRoot, absDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("a dry run can't copy %s, which is outside of the workspace directory %s", dir, root)
+	}
+	return rel, nil
+}
+
+// scratchCopy copies dir to a new temporary directory for a dry run, and returns it along with
+// the function removing it.
+func scratchCopy(dir string) (string, func(), error) {
+	scratch, err := os.MkdirTemp("", "gobump-dry-run-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create the dry-run directory: %w", err)
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(scratch); err != nil {
+			log.Printf("Warning: failed to remove the dry-run directory %s: %v", scratch, err)
+		}
+	}
+
+	log.Printf("Dry run: copying %s to %s ...\n", dir, scratch)
+	if err := copyTree(dir, scratch); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to copy %s for the dry run: %w", dir, err)
+	}
+	return scratch, cleanup, nil
+}
+
+// absWorkDirReplaces rewrites the relative directory replaces of the go.work at workPath, a copy
+// of the one of workdir, to absolute paths under workdir.
+func absWorkDirReplaces(workPath, workdir string) error {
+	workFile, err := ParseGoWorkfile(workPath)
+	if err != nil {
+		return err
+	}
+	absRoot, err := filepath.Abs(workdir)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, r := range workFile.Replace {
+		if r.New.Version != "" || !modfile.IsDirectoryPath(r.New.Path) || filepath.IsAbs(r.New.Path) {
+			continue
+		}
+		abs := filepath.Join(absRoot, filepath.FromSlash(r.New.Path))
+		if err := workFile.AddReplace(r.Old.Path, r.Old.Version, abs, ""); err != nil {
+			return err
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return writeFormatted(workPath, func() ([]byte, error) {
+		return modfile.Format(workFile.Syntax), nil
+	})
+}
+
+// absDirReplaces rewrites the relative directory replaces of the go.mod at modpath, a copy of the
+// one of modroot, to absolute paths under modroot. It returns the original path of every rewritten one.
+func absDirReplaces(modpath, modroot string) (map[string]string, error) {
+	modFile, _, err := ParseGoModfile(modpath)
+	if err != nil {
+		return nil, err
+	}
+	absRoot, err := filepath.Abs(modroot)
+	if err != nil {
+		return nil, err
+	}
+	dirs := map[string]string{}
+	for _, r := range modFile.Replace {
+		if r.New.Version != "" || !modfile.IsDirectoryPath(r.New.Path) || filepath.IsAbs(r.New.Path) {
+			continue
+		}
+		dir := r.New.Path
+		abs := filepath.Join(absRoot, filepath.FromSlash(dir))
+		if err := modFile.AddReplace(r.Old.Path, r.Old.Version, abs, ""); err != nil {
+			return nil, err
+		}
+		dirs[abs] = dir
+	}
+	if len(dirs) == 0 {
+		return dirs, nil
+	}
+	return dirs, writeFormatted(modpath, modFile.Format)
+}
+
+// withGoWork returns a runner running the go commands of r with GOWORK set to gowork.
+func withGoWork(r run.Runner, gowork string) run.Runner {
+	switch r := r.(type) {
+	case nil:
+		return &run.ExecRunner{Env: []string{"GOWORK=" + gowork}}
+	case *run.ExecRunner:
+		c := *r
+		c.Env = append(slices.Clone(r.Env), "GOWORK="+gowork)
+		return &c
+	}
+	return &goWorkRunner{runner: r, gowork: gowork}
+}
+
+// goWorkRunner tells the update the GOWORK of its commands is gowork. The runner it wraps isn't
+// an ExecRunner, so it has to run its commands accordingly.
+type goWorkRunner struct {
+	runner run.Runner
+	gowork string
+}
+
+// Run implements run.Runner.
+func (r *goWorkRunner) Run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	return r.runner.Run(ctx, dir, args...)
+}
+
+// Getenv returns gowork for GOWORK, and the environment variable key of the wrapped runner otherwise.
+func (r *goWorkRunner) Getenv(key string) string {
+	if key == "GOWORK" {
+		return r.gowork
+	}
+	return run.Getenv(r.runner, key)
+}
+
+// copyTree copies the contents of src into dst, skipping the .git directory.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0o750)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyRegularFile(p, target)
+		}
+		return nil
+	})
+}
+
+func copyRegularFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm()) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,96 @@
+package update
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MainModuleError is returned when the main module itself is requested to be bumped.
+type MainModuleError struct {
+	Module string
+}
+
+func (e *MainModuleError) Error() string {
+	return fmt.Sprintf("bumping the main module is not allowed %q", e.Module)
+}
+
+// NotFoundError is returned when, after the update, a requested package is neither
+// required nor replaced in go.mod.
+type NotFoundError struct {
+	Package string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("package %s was not found on the go.mod file. Please remove the package or add it to the list of 'replaces'", e.Package)
+}
+
+// NotInGraphError is returned when a requested package is neither in go.mod nor in the
+// module graph of the module, so there is nothing to bump.
+type NotInGraphError struct {
+	Package string
+	// Err is the failure of the module graph lookup.
+	Err error
+}
+
+func (e *NotInGraphError) Error() string {
+	return fmt.Sprintf("package %s was not found on the go.mod file nor in its module graph. Please remove the package or add it to the list of 'replaces'", e.Package)
+}
+
+// Unwrap returns the failure of the module graph lookup.
+func (e *NotInGraphError) Unwrap() error {
+	return e.Err
+}
+
+// BelowRequestedError is returned when, after the update, go.mod holds an older
+// version of a package than the requested one.
+type BelowRequestedError struct {
+	Package   string
+	Version   string
+	Requested string
+}
+
+func (e *BelowRequestedError) Error() string {
+	return fmt.Sprintf("package %s with %s is less than the desired version %s", e.Package, e.Version, e.Requested)
+}
+
+// NotExactError is returned when, after the update, go.mod holds another version of a
+// package pinned with the exact mode, e.g. because another dependency requires a newer one.
+type NotExactError struct {
+	Package   string
+	Version   string
+	Requested string
+}
+
+func (e *NotExactError) Error() string {
+	return fmt.Sprintf("package %s with %s is not the exact version %s", e.Package, e.Version, e.Requested)
+}
+
+// QueryMismatchError is returned when, after the update, go.mod holds a version of a
+// package that does not satisfy its version query.
+type QueryMismatchError struct {
+	Package string
+	Version string
+	Query   string
+}
+
+func (e *QueryMismatchError) Error() string {
+	return fmt.Sprintf("package %s: version %s does not match query %q", e.Package, e.Version, e.Query)
+}
+
+// GoVersionError is returned when the update raised the go or toolchain line of go.mod above
+// the maximum go version. Culprits names the bumped modules whose go.mod requires a newer go.
+type GoVersionError struct {
+	Directive string
+	Before    string
+	After     string
+	Max       string
+	Culprits  []string
+}
+
+func (e *GoVersionError) Error() string {
+	msg := fmt.Sprintf("the update raised the %s line from %q to %q, above the maximum go version %s", e.Directive, e.Before, e.After, e.Max)
+	if len(e.Culprits) > 0 {
+		msg += ", required by " + strings.Join(e.Culprits, ", ")
+	}
+	return msg
+}
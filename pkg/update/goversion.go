@@ -0,0 +1,81 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"go/version"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// detectGoVersion returns the go version to tidy the module with, from the first source of
+// cfg.GoVersionSources yielding one, along with that source.
+func detectGoVersion(ctx context.Context, runner run.Runner, cfg *types.Config) (string, types.GoVersionSource, error) {
+	sources := cfg.GoVersionSources
+	if len(sources) == 0 {
+		sources = types.DefaultGoVersionSources
+	}
+	for _, source := range sources {
+		if !knownGoVersionSource(source) {
+			return "", "", fmt.Errorf("unknown Go version source %q", source)
+		}
+	}
+	for _, source := range sources {
+		goVersion, err := goVersionFrom(ctx, runner, cfg, source)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get the Go version from %s: %w", source, err)
+		}
+		if goVersion != "" {
+			log.Printf("Using Go version %s from %s\n", goVersion, source)
+			return goVersion, source, nil
+		}
+	}
+	return "", "", fmt.Errorf("none of the Go version sources %v has a Go version", sources)
+}
+
+// goVersionFrom returns the go version of source, or an empty string when it has none.
+func goVersionFrom(ctx context.Context, runner run.Runner, cfg *types.Config, source types.GoVersionSource) (string, error) {
+	switch source {
+	case types.GoVersionSourceFlag:
+		return cfg.GoVersion, nil
+	case types.GoVersionSourceEnv:
+		return getGoVersionFromEnvironment(ctx, runner, cfg.Modroot)
+	case types.GoVersionSourceGoMod, types.GoVersionSourceToolchain:
+		modFile, _, err := ParseGoModfile(path.Join(cfg.Modroot, "go.mod"))
+		if err != nil {
+			return "", err
+		}
+		if source == types.GoVersionSourceGoMod && modFile.Go != nil {
+			return modFile.Go.Version, nil
+		}
+		if source == types.GoVersionSourceToolchain && modFile.Toolchain != nil && version.IsValid(modFile.Toolchain.Name) {
+			return strings.TrimPrefix(modFile.Toolchain.Name, "go"), nil
+		}
+		return "", nil
+	case types.GoVersionSourceGoWork:
+		workPath := run.FindGoWork(runner, cfg.Modroot)
+		if workPath == "" {
+			return "", nil
+		}
+		workFile, err := ParseGoWorkfile(workPath)
+		if err != nil {
+			return "", err
+		}
+		if workFile.Go != nil {
+			return workFile.Go.Version, nil
+		}
+	}
+	return "", nil
+}
+
+func knownGoVersionSource(source types.GoVersionSource) bool {
+	switch source {
+	case types.GoVersionSourceFlag, types.GoVersionSourceEnv, types.GoVersionSourceGoMod, types.GoVersionSourceGoWork, types.GoVersionSourceToolchain:
+		return true
+	}
+	return false
+}
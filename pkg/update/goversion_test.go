@@ -0,0 +1,119 @@
+package update
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+func TestDetectGoVersion(t *testing.T) {
+	testCases := []struct {
+		name       string
+		goVersion  string
+		sources    []types.GoVersionSource
+		goWork     string
+		want       string
+		wantSource types.GoVersionSource
+		wantErr    string
+	}{
+		{
+			name:       "flag",
+			goVersion:  "1.21",
+			sources:    []types.GoVersionSource{types.GoVersionSourceFlag, types.GoVersionSourceGoMod},
+			want:       "1.21",
+			wantSource: types.GoVersionSourceFlag,
+		},
+		{
+			name:       "falls back to go.mod without flag",
+			sources:    []types.GoVersionSource{types.GoVersionSourceFlag, types.GoVersionSourceGoMod},
+			want:       "1.22.1",
+			wantSource: types.GoVersionSourceGoMod,
+		},
+		{
+			name:       "toolchain",
+			sources:    []types.GoVersionSource{types.GoVersionSourceToolchain},
+			want:       "1.22.5",
+			wantSource: types.GoVersionSourceToolchain,
+		},
+		{
+			name:       "go.work",
+			sources:    []types.GoVersionSource{types.GoVersionSourceGoWork},
+			goWork:     "go 1.23\n\nuse .\n",
+			want:       "1.23",
+			wantSource: types.GoVersionSourceGoWork,
+		},
+		{
+			name:    "no go.work",
+			sources: []types.GoVersionSource{types.GoVersionSourceGoWork},
+			wantErr: "none of the Go version sources [gowork] has a Go version",
+		},
+		{
+			name:    "unknown source",
+			sources: []types.GoVersionSource{types.GoVersionSourceGoMod, "runtime"},
+			wantErr: `unknown Go version source "runtime"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GOWORK", "")
+			modroot := t.TempDir()
+			gomod := "module example.com/app\n\ngo 1.22.1\n\ntoolchain go1.22.5\n"
+			if err := os.WriteFile(filepath.Join(modroot, "go.mod"), []byte(gomod), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if tc.goWork != "" {
+				if err := os.WriteFile(filepath.Join(modroot, "go.work"), []byte(tc.goWork), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, source, err := detectGoVersion(context.Background(), nil, &types.Config{
+				Modroot:          modroot,
+				GoVersion:        tc.goVersion,
+				GoVersionSources: tc.sources,
+			})
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("detectGoVersion() = %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want || source != tc.wantSource {
+				t.Errorf("detectGoVersion() = %q, %q, want %q, %q", got, source, tc.want, tc.wantSource)
+			}
+		})
+	}
+}
+
+func TestTidyKeepsGoModVersion(t *testing.T) {
+	runner, modroot := fooProxyRunner(t)
+	// Tidy would otherwise drop the unused requirement.
+	main := "package main\n\nimport _ \"example.com/foo\"\n\nfunc main() {}\n"
+	if err := os.WriteFile(filepath.Join(modroot, "main.go"), []byte(main), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	result, err := DoUpdateWithResult(map[string]*types.Package{
+		"example.com/foo": {Name: "example.com/foo", Version: "v1.2.5"},
+	}, &types.Config{
+		Modroot:          modroot,
+		Runner:           runner,
+		Tidy:             true,
+		GoVersionSources: []types.GoVersionSource{types.GoVersionSourceGoMod},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.GoVersion != "1.22" || result.GoVersionSource != types.GoVersionSourceGoMod {
+		t.Errorf("result Go version = %q from %q, want 1.22 from gomod", result.GoVersion, result.GoVersionSource)
+	}
+	if got := result.ModFile.Go.Version; got != "1.22" {
+		t.Errorf("go directive = %q, want it kept at 1.22", got)
+	}
+}
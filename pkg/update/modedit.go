@@ -0,0 +1,87 @@
+package update
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/mod/modfile"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// applyModfileEdits applies the replace and require edits for the packages in order
+// to the go.mod at modpath, whose current content is content, writing it only once.
+func applyModfileEdits(modpath string, content []byte, pkgVersions map[string]*types.Package, order []string) error {
+	modFile, err := modfile.Parse(filepath.Base(modpath), content, nil)
+	if err != nil {
+		return err
+	}
+	if err := editModFile(modFile, pkgVersions, order); err != nil {
+		return err
+	}
+	modFile.Cleanup()
+	out, err := modFile.Format()
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(modpath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(modpath, out, info.Mode().Perm())
+}
+
+// editModFile does in memory what the go backend does with 'go mod edit': replaces are applied
+// first, then the requires that 'go get' fetches are set in place, or dropped when their version
+// can't be written to go.mod.
+func editModFile(modFile *modfile.File, pkgVersions map[string]*types.Package, order []string) error {
+	for _, k := range order {
+		pkg := pkgVersions[k]
+		if !pkg.Replace {
+			continue
+		}
+		if pkg.OldVersion != "" {
+			if err := modFile.DropReplace(pkg.OldName, pkg.OldVersion); err != nil {
+				return fmt.Errorf("dropping replace of %s: %w", pkg.OldModule(), err)
+			}
+		} else if err := dropReplaces(modFile, pkg.OldName); err != nil {
+			return fmt.Errorf("dropping replace of %s: %w", pkg.OldName, err)
+		}
+		if err := modFile.AddReplace(pkg.OldName, pkg.OldVersion, pkg.Name, pkg.Version); err != nil {
+			return fmt.Errorf("replacing %s with %s@%s: %w", pkg.OldModule(), pkg.Name, pkg.Version, err)
+		}
+	}
+	for _, k := range order {
+		pkg := pkgVersions[k]
+		if pkg.Replace || !pkg.Require {
+			continue
+		}
+		if !canSetRequire(pkg) {
+			if err := modFile.DropRequire(pkg.Name); err != nil {
+				return fmt.Errorf("dropping require of %s: %w", pkg.Name, err)
+			}
+			continue
+		}
+		if err := modFile.AddRequire(pkg.Name, pkg.Version); err != nil {
+			return fmt.Errorf("requiring %s@%s: %w", pkg.Name, pkg.Version, err)
+		}
+	}
+	return nil
+}
+
+// dropReplaces drops the replacement of every version of oldPath, like 'go mod edit -dropreplace'.
+func dropReplaces(modFile *modfile.File, oldPath string) error {
+	var versions []string
+	for _, r := range modFile.Replace {
+		if r.Old.Path == oldPath {
+			versions = append(versions, r.Old.Version)
+		}
+	}
+	for _, v := range versions {
+		if err := modFile.DropReplace(oldPath, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
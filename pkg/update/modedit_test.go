@@ -0,0 +1,176 @@
+package update
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+func TestEditModFile(t *testing.T) {
+	tmpdir := t.TempDir()
+	copyFile(t, "testdata/aws-efs-csi-driver/go.mod", tmpdir)
+	modpath := filepath.Join(tmpdir, "go.mod")
+
+	_, content, err := ParseGoModfile(modpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgVersions := map[string]*types.Package{
+		"github.com/fakefuzz": {
+			OldName: "github.com/google/gofuzz",
+			Name:    "github.com/fakefuzz",
+			Version: "v1.2.3",
+			Replace: true,
+			Index:   0,
+		},
+		"k8s.io/client-go": {
+			OldName: "k8s.io/client-go",
+			Name:    "k8s.io/client-go",
+			Version: "v0.28.0",
+			Replace: true,
+			Index:   1,
+		},
+		"github.com/google/uuid": {
+			Name:    "github.com/google/uuid",
+			Version: "v1.4.0",
+			Require: true,
+			Index:   2,
+		},
+	}
+	if err := applyModfileEdits(modpath, content, pkgVersions, orderPkgVersionsMap(pkgVersions)); err != nil {
+		t.Fatalf("applyModfileEdits() = %v", err)
+	}
+
+	modFile, _, err := ParseGoModfile(modpath)
+	if err != nil {
+		t.Fatalf("edited go.mod does not parse: %v", err)
+	}
+	replaces := map[string]string{}
+	for _, r := range modFile.Replace {
+		if _, ok := replaces[r.Old.Path]; ok {
+			t.Errorf("duplicate replace for %s", r.Old.Path)
+		}
+		replaces[r.Old.Path] = r.New.Path + "@" + r.New.Version
+	}
+	for old, want := range map[string]string{
+		"github.com/google/gofuzz": "github.com/fakefuzz@v1.2.3",
+		"k8s.io/client-go":         "k8s.io/client-go@v0.28.0",
+		"k8s.io/api":               "k8s.io/api@v0.26.10",
+	} {
+		if got := replaces[old]; got != want {
+			t.Errorf("replace of %s: got %q, want %q", old, got, want)
+		}
+	}
+	for _, r := range modFile.Require {
+		if r.Mod.Path == "github.com/google/uuid" && r.Mod.Version != "v1.4.0" {
+			t.Errorf("expected the require of github.com/google/uuid to be set to v1.4.0, got %s", r.Mod.Version)
+		}
+	}
+	if info, err := os.Stat(modpath); err != nil || info.Size() == 0 {
+		t.Errorf("expected a non empty go.mod, got err = %v", err)
+	}
+}
+
+func TestModfileBackendUpdate(t *testing.T) {
+	tmpdir := t.TempDir()
+	copyFile(t, "testdata/aws-efs-csi-driver/go.mod", tmpdir)
+
+	pkgVersions := map[string]*types.Package{
+		"github.com/google/uuid": {
+			Name:    "github.com/google/uuid",
+			Version: "v1.4.0",
+			Index:   0,
+		},
+		"k8s.io/client-go": {
+			OldName: "k8s.io/client-go",
+			Name:    "k8s.io/client-go",
+			Version: "v0.28.0",
+			Index:   1,
+		},
+	}
+	modFile, err := DoUpdate(pkgVersions, &types.Config{Modroot: tmpdir, EditBackend: types.EditBackendModfile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := getVersion(modFile, "github.com/google/uuid"); got != "v1.4.0" {
+		t.Errorf("expected github.com/google/uuid v1.4.0, got %s", got)
+	}
+	if got := getVersion(modFile, "k8s.io/client-go"); got != "v0.28.0" {
+		t.Errorf("expected k8s.io/client-go v0.28.0, got %s", got)
+	}
+}
+
+func TestRequireLayoutKept(t *testing.T) {
+	const before = `module example.com/app
+
+go 1.22
+
+require (
+	example.com/baz v1.0.0
+	example.com/foo v1.2.3 // pinned for the v1.2 API
+)
+
+require (
+	example.com/bar v1.0.0 // indirect
+	example.com/qux v1.0.0 // indirect
+)
+`
+	const want = `module example.com/app
+
+go 1.22
+
+require (
+	example.com/baz v1.0.0
+	example.com/foo v1.2.5 // pinned for the v1.2 API
+)
+
+require (
+	example.com/bar v1.1.0 // indirect
+	example.com/qux v1.0.0 // indirect
+)
+`
+	testCases := []struct {
+		name string
+		cfg  types.Config
+	}{
+		{name: "go backend"},
+		{name: "go backend batched", cfg: types.Config{BatchGet: true}},
+		{name: "modfile backend", cfg: types.Config{EditBackend: types.EditBackendModfile}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := tempProxy(t)
+			writeProxy(t, proxy, "example.com/foo", "v1.2.3", "v1.2.5")
+			for _, module := range []string{"example.com/bar", "example.com/baz", "example.com/qux"} {
+				writeProxy(t, proxy, module, "v1.0.0", "v1.1.0")
+			}
+			runner := proxyRunner(t, proxy)
+			modroot := t.TempDir()
+			if err := os.WriteFile(filepath.Join(modroot, "go.mod"), []byte(before), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			pkgVersions := map[string]*types.Package{
+				"example.com/foo": {Name: "example.com/foo", Version: "v1.2.5", Index: 0},
+				"example.com/bar": {Name: "example.com/bar", Version: "v1.1.0", Index: 1},
+			}
+			cfg := tc.cfg
+			cfg.Modroot = modroot
+			cfg.Runner = runner
+			if _, err := DoUpdateWithResult(pkgVersions, &cfg); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(filepath.Join(modroot, "go.mod"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, string(got)); diff != "" {
+				t.Errorf("go.mod (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
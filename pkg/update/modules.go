@@ -0,0 +1,124 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// FindModules returns the sorted directory of every go.mod under root, root included. Like the go
+// command, it skips the vendor and testdata directories and the ones starting with "." or "_".
+func FindModules(root string) ([]string, error) {
+	var modroots []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if p != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == "go.mod" {
+			modroots = append(modroots, filepath.Dir(p))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look for go.mod files under %s: %w", root, err)
+	}
+	// Parents come before the modules nested in them.
+	sort.Strings(modroots)
+	return modroots, nil
+}
+
+// DoUpdateModules applies the same bumps to every module of cfg.Modroots, or to cfg.Modroot when it
+// is empty, and returns one result per module in the same order. A module is only updated with the
+// packages it requires or replaces, the other packages are left out of its result, and a module
+// depending on none of them is left untouched. It stops at the first module failing to update and
+// returns the results of the modules updated before along with the error. When cfg.Transactional
+// is set, or the update is canceled, those modules are rolled back instead and no result is returned.
+func DoUpdateModules(ctx context.Context, pkgVersions map[string]*types.Package, cfg *types.Config) ([]*types.Result, error) {
+	modroots := cfg.Modroots
+	if len(modroots) == 0 {
+		modroots = []string{cfg.Modroot}
+	}
+
+	results := make([]*types.Result, 0, len(modroots))
+	var snaps []*snapshot
+	fail := func(err error) ([]*types.Result, error) {
+		if len(snaps) == 0 || !(cfg.Transactional || isCanceled(err)) {
+			return results, err
+		}
+		log.Println("Update failed, rolling back the modules updated before ...")
+		for i := len(snaps) - 1; i >= 0; i-- {
+			if rerr := snaps[i].restore(); rerr != nil {
+				return results, fmt.Errorf("%w (rolling back the modules also failed: %v)", err, rerr)
+			}
+		}
+		return nil, err
+	}
+	for _, modroot := range modroots {
+		modFile, _, err := ParseGoModfile(path.Join(modroot, "go.mod"))
+		if err != nil {
+			return fail(fmt.Errorf("module %s: unable to parse the go mod file with error: %w", modroot, err))
+		}
+		deps := dependedPackages(modFile, pkgVersions)
+		if len(deps) == 0 {
+			log.Printf("Module %s doesn't depend on any requested package, skipping\n", modroot)
+			results = append(results, &types.Result{Modroot: modroot, Packages: []types.PackageResult{}, ModFile: modFile})
+			continue
+		}
+
+		log.Printf("Updating module %s ...\n", modroot)
+		modCfg := *cfg
+		modCfg.Modroot = modroot
+		modCfg.Modroots = nil
+		if !cfg.DryRun && mayRollBack(ctx, cfg) {
+			snap, err := takeSnapshot(snapshotPaths(cfg.Runner, modroot, cfg.ForceWork)...)
+			if err != nil {
+				return fail(fmt.Errorf("module %s: failed to snapshot the module before the update: %w", modroot, err))
+			}
+			snaps = append(snaps, snap)
+		}
+		result, err := DoUpdateContext(ctx, deps, &modCfg)
+		if err != nil {
+			return fail(fmt.Errorf("module %s: %w", modroot, err))
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// dependedPackages returns a copy of the packages of pkgVersions that modFile requires or replaces.
+// The update edits the packages it is given, so every module gets its own copy.
+func dependedPackages(modFile *modfile.File, pkgVersions map[string]*types.Package) map[string]*types.Package {
+	paths := make(map[string]bool, len(modFile.Require)+2*len(modFile.Replace))
+	for _, req := range modFile.Require {
+		paths[req.Mod.Path] = true
+	}
+	for _, replace := range modFile.Replace {
+		paths[replace.Old.Path] = true
+		paths[replace.New.Path] = true
+	}
+
+	deps := map[string]*types.Package{}
+	for k, pkg := range pkgVersions {
+		if paths[pkg.Name] || (pkg.OldName != "" && paths[pkg.OldName]) {
+			p := *pkg
+			deps[k] = &p
+		}
+	}
+	return deps
+}
@@ -0,0 +1,141 @@
+package update
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// writeModule writes a go.mod for module in dir, requiring the given module@version entries.
+func writeModule(t *testing.T, dir, module string, requires ...string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	gomod := "module " + module + "\n\ngo 1.22\n"
+	for _, req := range requires {
+		gomod += "\nrequire " + req + "\n"
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindModules(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, "example.com/root")
+	writeModule(t, filepath.Join(root, "sub", "a"), "example.com/root/sub/a")
+	writeModule(t, filepath.Join(root, "vendor", "example.com", "v"), "example.com/v")
+	writeModule(t, filepath.Join(root, "testdata", "t"), "example.com/t")
+	writeModule(t, filepath.Join(root, ".hidden"), "example.com/hidden")
+	writeModule(t, filepath.Join(root, "_ignored"), "example.com/ignored")
+
+	got, err := FindModules(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{root, filepath.Join(root, "sub", "a")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FindModules() (-want +got)\n%s", diff)
+	}
+}
+
+func TestDoUpdateModules(t *testing.T) {
+	runner, _ := fooProxyRunner(t)
+	root := t.TempDir()
+	writeModule(t, root, "example.com/root")
+	writeModule(t, filepath.Join(root, "a"), "example.com/root/a", "example.com/foo v1.2.3")
+	writeModule(t, filepath.Join(root, "b"), "example.com/root/b", "example.com/foo v1.0.0")
+
+	modroots, err := FindModules(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgVersions := map[string]*types.Package{
+		"example.com/foo": {Name: "example.com/foo", Version: "v1.2.5"},
+	}
+	results, err := DoUpdateModules(context.Background(), pkgVersions, &types.Config{Modroots: modroots, Runner: runner})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type moduleResult struct {
+		Modroot  string
+		Changed  bool
+		Packages int
+		After    string
+	}
+	var got []moduleResult
+	for _, r := range results {
+		got = append(got, moduleResult{r.Modroot, r.Changed, len(r.Packages), getVersion(r.ModFile, "example.com/foo")})
+	}
+	want := []moduleResult{
+		{Modroot: root},
+		{Modroot: filepath.Join(root, "a"), Changed: true, Packages: 1, After: "v1.2.5"},
+		{Modroot: filepath.Join(root, "b"), Changed: true, Packages: 1, After: "v1.2.5"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DoUpdateModules() (-want +got)\n%s", diff)
+	}
+	if pkgVersions["example.com/foo"].Require {
+		t.Error("the requested packages were modified by the update")
+	}
+}
+
+func TestDoUpdateModulesFailure(t *testing.T) {
+	testCases := []struct {
+		name          string
+		transactional bool
+	}{
+		{name: "partial results"},
+		{name: "transactional", transactional: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner, _ := fooProxyRunner(t)
+			root := t.TempDir()
+			writeModule(t, filepath.Join(root, "a"), "example.com/root/a", "example.com/foo v1.2.3")
+			// The proxy doesn't serve example.com/missing, so 'go get' fails in module b.
+			writeModule(t, filepath.Join(root, "b"), "example.com/root/b", "example.com/foo v1.2.3", "example.com/missing v1.0.0")
+			modpath := filepath.Join(root, "a", "go.mod")
+			before, err := os.ReadFile(modpath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pkgVersions := map[string]*types.Package{
+				"example.com/foo": {Name: "example.com/foo", Version: "v1.2.5"},
+			}
+			results, err := DoUpdateModules(context.Background(), pkgVersions, &types.Config{
+				Modroots:      []string{filepath.Join(root, "a"), filepath.Join(root, "b")},
+				Runner:        runner,
+				Transactional: tc.transactional,
+			})
+			if err == nil {
+				t.Fatal("DoUpdateModules() succeeded, want the failure of module b")
+			}
+
+			after, err := os.ReadFile(modpath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.transactional {
+				if len(results) != 0 || string(after) != string(before) {
+					t.Errorf("got %d results and module a:\n%s\nwant module a rolled back", len(results), after)
+				}
+				return
+			}
+			if len(results) != 1 || results[0].Modroot != filepath.Join(root, "a") || !results[0].Changed {
+				t.Fatalf("got %d results, want the one of the updated module a", len(results))
+			}
+			if string(after) == string(before) {
+				t.Error("module a was rolled back without --transactional")
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// PruneReplaces drops the replaces of a module by another version of itself, typically pins added
+// for a vulnerability, once the version go.mod requires is at least the pinned one. Replaces by
+// another module or a directory are left alone. go.mod is tidied afterwards when cfg.Tidy is set.
+// When cfg.DryRun is set, the prune runs against a scratch copy of the modroot and the original
+// module is never modified. Like DoUpdateContext, a failed prune restores the module files when
+// cfg.Transactional is set or it was canceled or timed out.
+func PruneReplaces(ctx context.Context, cfg *types.Config) (*types.PruneResult, error) {
+	if cfg.DryRun {
+		return doPruneDryRun(ctx, cfg)
+	}
+
+	var snap *snapshot
+	if mayRollBack(ctx, cfg) {
+		var err error
+		if snap, err = takeSnapshot(snapshotPaths(cfg.Runner, cfg.Modroot, cfg.ForceWork)...); err != nil {
+			return nil, fmt.Errorf("failed to snapshot the module before pruning: %w", err)
+		}
+	}
+
+	result, err := pruneReplaces(ctx, cfg)
+	if err != nil && snap != nil && (cfg.Transactional || isCanceled(err)) {
+		log.Println("Pruning failed, rolling back the module ...")
+		if rerr := snap.restore(); rerr != nil {
+			return nil, fmt.Errorf("%w (rolling back the module also failed: %v)", err, rerr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func pruneReplaces(ctx context.Context, cfg *types.Config) (*types.PruneResult, error) {
+	modpath := path.Join(cfg.Modroot, "go.mod")
+	modFile, content, err := ParseGoModfile(modpath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the go mod file with error: %w", err)
+	}
+
+	result := &types.PruneResult{Modroot: cfg.Modroot, Pruned: staleReplaces(modFile)}
+	for _, p := range result.Pruned {
+		log.Printf("Replace of %s by %s is stale, %s is required\n", p.Path, p.Version, p.Required)
+	}
+	if len(result.Pruned) == 0 {
+		return result, nil
+	}
+
+	runner := withTimeouts(cfg.Runner, cfg.Timeouts)
+	for _, p := range result.Pruned {
+		old := p.Path
+		if p.OldVersion != "" {
+			old += "@" + p.OldVersion
+		}
+		log.Printf("Running go mod edit -dropreplace %s ...\n", old)
+		if output, err := run.GoModEditDropReplaceModule(ctx, runner, old, cfg.Modroot); err != nil {
+			return nil, fmt.Errorf("failed to run 'go mod edit -dropreplace': %w for package %s with output: %v", err, old, output)
+		}
+	}
+
+	if cfg.Tidy {
+		goVersion, _, err := detectGoVersion(ctx, runner, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if output, err := run.GoModTidy(ctx, runner, cfg.Modroot, goVersion, cfg.TidyCompat); err != nil {
+			return nil, fmt.Errorf("failed to run 'go mod tidy': %w with output: %v", err, output)
+		}
+	}
+	if _, err := os.Stat(path.Join(cfg.Modroot, "vendor")); err == nil {
+		if output, err := run.GoVendor(ctx, runner, cfg.Modroot, cfg.ForceWork); err != nil {
+			return nil, fmt.Errorf("failed to run 'go vendor': %w with output: %v", err, output)
+		}
+	}
+
+	_, newContent, err := ParseGoModfile(modpath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the go mod file with error: %w", err)
+	}
+	result.Diff = cmp.Diff(string(content), string(newContent))
+	if cfg.ShowDiff && result.Diff != "" {
+		fmt.Println(result.Diff)
+	}
+	return result, nil
+}
+
+// staleReplaces returns the replaces of a required module by another version of itself that don't
+// raise its version anymore. A version-specific replace is also stale once that version isn't required.
+func staleReplaces(modFile *modfile.File) []types.PrunedReplace {
+	required := make(map[string]string, len(modFile.Require))
+	for _, req := range modFile.Require {
+		required[req.Mod.Path] = req.Mod.Version
+	}
+
+	var stale []types.PrunedReplace
+	for _, replace := range modFile.Replace {
+		if replace.New.Path != replace.Old.Path || replace.New.Version == "" {
+			continue
+		}
+		version, ok := required[replace.Old.Path]
+		if !ok {
+			continue
+		}
+		if semver.Compare(replace.New.Version, version) > 0 && (replace.Old.Version == "" || replace.Old.Version == version) {
+			continue
+		}
+		stale = append(stale, types.PrunedReplace{
+			Path:       replace.Old.Path,
+			OldVersion: replace.Old.Version,
+			Version:    replace.New.Version,
+			Required:   version,
+		})
+	}
+	return stale
+}
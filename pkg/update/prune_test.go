@@ -0,0 +1,95 @@
+package update
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/mod/modfile"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+func TestStaleReplaces(t *testing.T) {
+	const gomod = `module example.com/app
+
+go 1.22
+
+require (
+	example.com/a v1.3.0
+	example.com/b v1.2.0
+	example.com/c v1.2.0
+	example.com/d v1.2.0
+	example.com/e v1.2.0
+)
+
+replace (
+	example.com/a => example.com/a v1.2.4
+	example.com/b => example.com/b v1.2.4
+	example.com/c v1.1.0 => example.com/c v1.2.4
+	example.com/d => example.com/fork v1.0.0
+	example.com/e => ../e
+	example.com/unused => example.com/unused v1.0.0
+)
+`
+	modFile, err := modfile.Parse("go.mod", []byte(gomod), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []types.PrunedReplace{
+		{Path: "example.com/a", Version: "v1.2.4", Required: "v1.3.0"},
+		{Path: "example.com/c", OldVersion: "v1.1.0", Version: "v1.2.4", Required: "v1.2.0"},
+	}
+	if diff := cmp.Diff(want, staleReplaces(modFile)); diff != "" {
+		t.Errorf("staleReplaces() (-want +got)\n%s", diff)
+	}
+}
+
+func TestPruneReplaces(t *testing.T) {
+	runner, modroot := fooProxyRunner(t)
+	gomod := "module example.com/app\n\ngo 1.22\n\nrequire example.com/foo v1.3.0\n\nreplace example.com/foo => example.com/foo v1.2.5\n"
+	if err := os.WriteFile(filepath.Join(modroot, "go.mod"), []byte(gomod), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	main := "package main\n\nimport _ \"example.com/foo\"\n\nfunc main() {}\n"
+	if err := os.WriteFile(filepath.Join(modroot, "main.go"), []byte(main), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		result, err := PruneReplaces(context.Background(), &types.Config{
+			Modroot:          modroot,
+			Runner:           runner,
+			Tidy:             true,
+			DryRun:           dryRun,
+			GoVersionSources: []types.GoVersionSource{types.GoVersionSourceGoMod},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []types.PrunedReplace{{Path: "example.com/foo", Version: "v1.2.5", Required: "v1.3.0"}}
+		if diff := cmp.Diff(want, result.Pruned); diff != "" {
+			t.Errorf("dry run %v: pruned replaces (-want +got)\n%s", dryRun, diff)
+		}
+		if result.DryRun != dryRun || result.Modroot != modroot {
+			t.Errorf("dry run %v: got a result for %s with DryRun %v", dryRun, result.Modroot, result.DryRun)
+		}
+		if !strings.Contains(result.Diff, "replace example.com/foo => example.com/foo v1.2.5") {
+			t.Errorf("dry run %v: diff %q does not drop the replace", dryRun, result.Diff)
+		}
+
+		modFile, _, err := ParseGoModfile(filepath.Join(modroot, "go.mod"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := len(modFile.Replace), map[bool]int{true: 1, false: 0}[dryRun]; got != want {
+			t.Errorf("dry run %v: go.mod has %d replaces, want %d", dryRun, got, want)
+		}
+		if len(modFile.Require) != 1 || modFile.Require[0].Mod.Version != "v1.3.0" {
+			t.Errorf("dry run %v: requires = %v, want example.com/foo v1.3.0", dryRun, modFile.Require)
+		}
+	}
+}
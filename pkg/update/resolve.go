@@ -0,0 +1,59 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"golang.org/x/mod/modfile"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// resolveQueries resolves the version queries in pkgVersions, and the versions of the packages in the
+// latest-patch-above mode, to concrete versions using the module proxy. When there is any to resolve,
+// it returns a copy with the resolved versions, leaving the caller's packages untouched, and the
+// original query of every resolved query.
+func resolveQueries(ctx context.Context, runner run.Runner, modroot string, modFile *modfile.File, pkgVersions map[string]*types.Package) (map[string]*types.Package, map[string]string, error) {
+	resolved := make(map[string]*types.Package, len(pkgVersions))
+	queries := map[string]string{}
+	changed := false
+	for k, pkg := range pkgVersions {
+		resolved[k] = pkg
+		query := pkg.Version
+		switch {
+		case pkg.Mode == types.VersionModeLatestPatchAbove:
+			// The latest patch above v1.2.3 is the latest release matching ~v1.2.3.
+			query = "~" + pkg.Version
+		case !types.IsVersionQuery(pkg.Version):
+			continue
+		}
+		q, err := types.ParseVersionQuery(query)
+		if err != nil {
+			return nil, nil, fmt.Errorf("package %s: %w", pkg.Name, err)
+		}
+		// Querying @latest rather than the module itself doesn't require the version in go.mod to be available.
+		info, err := run.GoListModule(ctx, runner, modroot, pkg.Name+"@latest", true)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve version query %q for package %s: %w", query, pkg.Name, err)
+		}
+		version, err := q.Select(getVersion(modFile, pkg.Name), info.Version, info.Versions)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve version query for package %s: %w", pkg.Name, err)
+		}
+		log.Printf("Resolved %s@%s to %s\n", pkg.Name, pkg.Version, version)
+
+		p := *pkg
+		p.Version = version
+		resolved[k] = &p
+		if query == pkg.Version {
+			queries[k] = query
+		}
+		changed = true
+	}
+	if !changed {
+		return pkgVersions, queries, nil
+	}
+	return resolved, queries, nil
+}
@@ -0,0 +1,184 @@
+package update
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// writeProxy lays out a file:// GOPROXY in dir serving every version of module.
+func writeProxy(t *testing.T, dir, module string, versions ...string) {
+	t.Helper()
+	writeProxyGo(t, dir, module, "1.22", versions...)
+}
+
+// writeProxyGo is like writeProxy, with goVersion as the go directive of the served versions.
+func writeProxyGo(t *testing.T, dir, module, goVersion string, versions ...string) {
+	t.Helper()
+	writeProxyMod(t, dir, module, fmt.Sprintf("module %s\n\ngo %s\n", module, goVersion), versions...)
+}
+
+// writeProxyMod is like writeProxy, with gomod as the go.mod of the served versions.
+func writeProxyMod(t *testing.T, dir, module, gomod string, versions ...string) {
+	t.Helper()
+	vdir := filepath.Join(dir, module, "@v")
+	if err := os.MkdirAll(vdir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range versions {
+		files := map[string]string{
+			v + ".info": fmt.Sprintf(`{"Version":%q,"Time":"2024-01-01T00:00:00Z"}`, v),
+			v + ".mod":  gomod,
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(vdir, name), []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		f, err := os.Create(filepath.Join(vdir, v+".zip"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		zw := zip.NewWriter(f)
+		for name, content := range map[string]string{"go.mod": gomod, "foo.go": "package foo\n"} {
+			w, err := zw.Create(fmt.Sprintf("%s@%s/%s", module, v, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(vdir, "list"), []byte(strings.Join(versions, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tempProxy returns a directory for a file:// GOPROXY. GOPROXY is a comma-separated list, so the
+// proxy can't live in t.TempDir(), named after the test.
+func tempProxy(t *testing.T) string {
+	t.Helper()
+	proxy, err := os.MkdirTemp("", "gobump-proxy-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(proxy) })
+	return proxy
+}
+
+// proxyRunner returns a runner resolving modules against the file:// proxy, outside of any workspace
+// and with its own module cache. The entries of extraEnv, e.g. GOWORK=/path/go.work, win over these.
+func proxyRunner(t *testing.T, proxy string, extraEnv ...string) *run.ExecRunner {
+	t.Helper()
+	env := []string{
+		"GOPROXY=file://" + filepath.ToSlash(proxy),
+		"GOSUMDB=off",
+		"GOFLAGS=-mod=mod",
+		"GOWORK=off",
+		"GOMODCACHE=" + t.TempDir(),
+	}
+	return &run.ExecRunner{Env: append(env, extraEnv...)}
+}
+
+// fooProxyRunner returns a runner resolving modules against a file:// proxy serving example.com/foo,
+// and a modroot requiring example.com/foo v1.2.3.
+func fooProxyRunner(t *testing.T) (run.Runner, string) {
+	t.Helper()
+	proxy := tempProxy(t)
+	writeProxy(t, proxy, "example.com/foo", "v1.0.0", "v1.2.3", "v1.2.5", "v1.3.0", "v1.4.0-rc.1")
+	runner := proxyRunner(t, proxy)
+
+	modroot := t.TempDir()
+	gomod := "module example.com/app\n\ngo 1.22\n\nrequire example.com/foo v1.2.3\n"
+	if err := os.WriteFile(filepath.Join(modroot, "go.mod"), []byte(gomod), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return runner, modroot
+}
+
+func TestVersionQueries(t *testing.T) {
+	testCases := []struct {
+		query   string
+		want    string
+		wantErr string
+	}{
+		{query: "latest", want: "v1.3.0"},
+		{query: "patch", want: "v1.2.5"},
+		{query: "~v1.2.0", want: "v1.2.5"},
+		{query: ">=v1.2.3,<v1.3", want: "v1.2.5"},
+		{query: "^v2", wantErr: `no version matches query "^v2"`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			runner, modroot := fooProxyRunner(t)
+			pkgVersions := map[string]*types.Package{
+				"example.com/foo": {Name: "example.com/foo", Version: tc.query},
+			}
+			result, err := DoUpdateWithResult(pkgVersions, &types.Config{Modroot: modroot, Runner: runner})
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("DoUpdateWithResult() = %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := result.Packages[0]
+			if got.RequestedVersion != tc.want || got.Query != tc.query || got.After != tc.want {
+				t.Errorf("package result = %+v, want %s resolved from %q", got, tc.want, tc.query)
+			}
+			if v := pkgVersions["example.com/foo"].Version; v != tc.query {
+				t.Errorf("requested version was changed to %q, want %q", v, tc.query)
+			}
+		})
+	}
+}
+
+func TestVersionModes(t *testing.T) {
+	testCases := []struct {
+		name        string
+		version     string
+		mode        types.VersionMode
+		wantVersion string
+		wantAfter   string
+		wantSkipped bool
+	}{
+		{name: "minimum keeps a newer version", version: "v1.0.0", wantVersion: "v1.0.0", wantAfter: "v1.2.3", wantSkipped: true},
+		{name: "exact downgrades", version: "v1.0.0", mode: types.VersionModeExact, wantVersion: "v1.0.0", wantAfter: "v1.0.0"},
+		{name: "exact upgrades", version: "v1.2.5", mode: types.VersionModeExact, wantVersion: "v1.2.5", wantAfter: "v1.2.5"},
+		{name: "latest patch above", version: "v1.2.4", mode: types.VersionModeLatestPatchAbove, wantVersion: "v1.2.5", wantAfter: "v1.2.5"},
+		{name: "latest patch above keeps a newer minor", version: "v1.0.0", mode: types.VersionModeLatestPatchAbove, wantVersion: "v1.0.0", wantAfter: "v1.2.3", wantSkipped: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner, modroot := fooProxyRunner(t)
+			pkgVersions := map[string]*types.Package{
+				"example.com/foo": {Name: "example.com/foo", Version: tc.version, Mode: tc.mode},
+			}
+			result, err := DoUpdateWithResult(pkgVersions, &types.Config{Modroot: modroot, Runner: runner})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := result.Packages[0]
+			if got.RequestedVersion != tc.wantVersion || got.After != tc.wantAfter || got.Skipped != tc.wantSkipped || got.Mode != tc.mode {
+				t.Errorf("package result = %+v, want requested %s, after %s, skipped %v", got, tc.wantVersion, tc.wantAfter, tc.wantSkipped)
+			}
+		})
+	}
+}
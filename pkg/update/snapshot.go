@@ -0,0 +1,80 @@
+package update
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
+)
+
+// snapshotFile is the saved state of a single file.
+type snapshotFile struct {
+	exists  bool
+	content []byte
+	mode    fs.FileMode
+}
+
+// snapshot keeps the content of the files an update may modify so they can be restored.
+type snapshot struct {
+	files map[string]*snapshotFile
+}
+
+// snapshotPaths lists the files of modroot, and of its workspace if any, that an update may modify.
+func snapshotPaths(r run.Runner, modroot string, forceWork bool) []string {
+	paths := []string{
+		filepath.Join(modroot, "go.mod"),
+		filepath.Join(modroot, "go.sum"),
+		filepath.Join(modroot, "vendor", "modules.txt"),
+	}
+	workPath := run.FindGoWork(r, modroot)
+	if workPath == "" && forceWork {
+		// Mirror run.UpdateGoWorkVersion, which falls back to the current directory.
+		workPath = run.FindGoWork(r, ".")
+	}
+	if workPath != "" {
+		paths = append(paths, workPath, workPath+".sum")
+	}
+	return paths
+}
+
+// takeSnapshot saves the content of the given files.
+func takeSnapshot(paths ...string) (*snapshot, error) {
+	s := &snapshot{files: make(map[string]*snapshotFile, len(paths))}
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		info, err := os.Stat(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			s.files[p] = &snapshotFile{}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		s.files[p] = &snapshotFile{exists: true, content: content, mode: info.Mode().Perm()}
+	}
+	return s, nil
+}
+
+// restore puts every file back in the state it had when the snapshot was taken.
+func (s *snapshot) restore() error {
+	var errs []error
+	for p, f := range s.files {
+		if !f.exists {
+			if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := os.WriteFile(p, f.content, f.mode); err != nil {
+			errs = append(errs, fmt.Errorf("restoring %s: %w", p, err))
+		}
+	}
+	return errors.Join(errs...)
+}
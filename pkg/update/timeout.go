@@ -0,0 +1,63 @@
+package update
+
+import (
+	"context"
+	"time"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// timeoutRunner bounds every go command it runs by the timeout configured for its step.
+type timeoutRunner struct {
+	runner   run.Runner
+	timeouts types.Timeouts
+}
+
+// withTimeouts wraps r so its commands are bounded by timeouts. A nil r means run.ExecRunner.
+func withTimeouts(r run.Runner, timeouts types.Timeouts) run.Runner {
+	if r == nil {
+		r = &run.ExecRunner{}
+	}
+	if timeouts == (types.Timeouts{}) {
+		return r
+	}
+	return &timeoutRunner{runner: r, timeouts: timeouts}
+}
+
+// Run implements run.Runner.
+func (r *timeoutRunner) Run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	if timeout := r.timeoutFor(args); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return r.runner.Run(ctx, dir, args...)
+}
+
+// Getenv returns the environment variable key of the commands of the wrapped runner.
+func (r *timeoutRunner) Getenv(key string) string {
+	return run.Getenv(r.runner, key)
+}
+
+// timeoutFor returns the timeout of the step the go command with args belongs to.
+func (r *timeoutRunner) timeoutFor(args []string) time.Duration {
+	if len(args) == 0 {
+		return 0
+	}
+	if args[0] == "get" {
+		return r.timeouts.Get
+	}
+	if len(args) < 2 {
+		return 0
+	}
+	switch args[1] {
+	case "tidy":
+		return r.timeouts.Tidy
+	case "edit":
+		return r.timeouts.Edit
+	case "vendor":
+		return r.timeouts.Vendor
+	}
+	return 0
+}
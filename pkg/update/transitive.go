@@ -0,0 +1,88 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// checkTransitive looks up in the module graph the packages of pkgVersions that go.mod neither
+// requires nor replaces, and returns them in order. Such a package is only reached through other
+// dependencies and gets bumped with an indirect require, while a package missing from the graph
+// too is an error. The version the graph selects is recorded in the report and, like
+// checkPackageValues does for go.mod, a package older than it is skipped unless it allows downgrades.
+func checkTransitive(ctx context.Context, runner run.Runner, modroot string, pkgVersions map[string]*types.Package, report map[string]*types.PackageResult) ([]string, error) {
+	var transitive []string
+	for _, k := range orderPkgVersionsMap(pkgVersions) {
+		pkg := pkgVersions[k]
+		if pkg.Require || pkg.Replace {
+			continue
+		}
+		info, err := run.GoListModule(ctx, runner, modroot, pkg.Name, false)
+		if err != nil {
+			if isCanceled(err) {
+				return nil, err
+			}
+			return nil, &NotInGraphError{Package: pkg.Name, Err: err}
+		}
+		log.Printf("Package %s is not in go.mod, but the module graph selects %s\n", pkg.Name, info.Version)
+		r, ok := report[k]
+		if ok {
+			r.Before = info.Version
+			r.Transitive = true
+		}
+		if semver.IsValid(pkg.Version) && !pkg.AllowsDowngrade() && semver.Compare(info.Version, pkg.Version) > 0 {
+			msg := fmt.Sprintf("requested version %q is older than current version %q", pkg.Version, info.Version)
+			log.Printf("Warning: package %s: %s, skipping", pkg.Name, msg)
+			if ok {
+				r.Skipped = true
+				r.SkipReason = types.SkipReasonDowngrade
+				r.Message = msg
+			}
+			delete(pkgVersions, k)
+			continue
+		}
+		transitive = append(transitive, k)
+	}
+	return transitive, nil
+}
+
+// keepTransitive adds back as indirect requires the transitive packages that 'go mod tidy' dropped
+// from the go.mod at modpath, because no package of the build comes from them. Their requested
+// version still applies to the module graph.
+func keepTransitive(modpath string, pkgVersions map[string]*types.Package, transitive []string) error {
+	content, err := os.ReadFile(filepath.Clean(modpath))
+	if err != nil {
+		return err
+	}
+	modFile, err := modfile.Parse(filepath.Base(modpath), content, nil)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, k := range transitive {
+		pkg := pkgVersions[k]
+		if getVersion(modFile, pkg.Name) != "" {
+			continue
+		}
+		log.Printf("Adding back %s@%s as an indirect require ...\n", pkg.Name, pkg.Version)
+		modFile.AddNewRequire(pkg.Name, pkg.Version, true)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	modFile.Cleanup()
+	if err := writeFormatted(modpath, modFile.Format); err != nil {
+		return fmt.Errorf("failed to write the go mod file: %w", err)
+	}
+	return nil
+}
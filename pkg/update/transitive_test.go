@@ -0,0 +1,111 @@
+package update
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+func TestTransitivePackages(t *testing.T) {
+	testCases := []struct {
+		name    string
+		version string
+		tidy    bool
+		want    types.PackageResult
+		wantErr bool
+	}{
+		{
+			name:    "added as indirect",
+			version: "v1.1.0",
+			want:    types.PackageResult{Kind: types.KindRequire, RequestedVersion: "v1.1.0", Before: "v1.0.0", After: "v1.1.0", Transitive: true},
+		},
+		{
+			name:    "kept after tidy",
+			version: "v1.1.0",
+			tidy:    true,
+			want:    types.PackageResult{Kind: types.KindRequire, RequestedVersion: "v1.1.0", Before: "v1.0.0", After: "v1.1.0", Transitive: true},
+		},
+		{
+			name:    "downgrade skipped",
+			version: "v0.9.0",
+			want: types.PackageResult{
+				Kind:             types.KindRequire,
+				RequestedVersion: "v0.9.0",
+				Before:           "v1.0.0",
+				Transitive:       true,
+				Skipped:          true,
+				SkipReason:       types.SkipReasonDowngrade,
+				Message:          `requested version "v0.9.0" is older than current version "v1.0.0"`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner, modroot := transitiveProxyRunner(t)
+			pkgVersions := map[string]*types.Package{
+				"example.com/bar": {Name: "example.com/bar", Version: tc.version},
+			}
+			result, err := DoUpdateWithResult(pkgVersions, &types.Config{
+				Modroot:          modroot,
+				Runner:           runner,
+				Tidy:             tc.tidy,
+				GoVersionSources: []types.GoVersionSource{types.GoVersionSourceGoMod},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.want.Name = "example.com/bar"
+			if diff := cmp.Diff([]types.PackageResult{tc.want}, result.Packages); diff != "" {
+				t.Errorf("package results (-want +got)\n%s", diff)
+			}
+			if tc.want.Skipped {
+				return
+			}
+			for _, req := range result.ModFile.Require {
+				if req.Mod.Path == "example.com/bar" && !req.Indirect {
+					t.Errorf("example.com/bar is required without the indirect comment")
+				}
+			}
+		})
+	}
+}
+
+func TestNotInGraph(t *testing.T) {
+	runner, modroot := transitiveProxyRunner(t)
+	pkgVersions := map[string]*types.Package{
+		"example.com/baz": {Name: "example.com/baz", Version: "v1.0.0"},
+	}
+	_, err := DoUpdateWithResult(pkgVersions, &types.Config{Modroot: modroot, Runner: runner})
+	var notInGraph *NotInGraphError
+	if !errors.As(err, &notInGraph) || notInGraph.Package != "example.com/baz" {
+		t.Fatalf("DoUpdateWithResult() = %v, want a *NotInGraphError for example.com/baz", err)
+	}
+}
+
+// transitiveProxyRunner returns a runner resolving modules against a file:// proxy, and a modroot
+// importing example.com/foo v1.2.3, which requires example.com/bar v1.0.0.
+func transitiveProxyRunner(t *testing.T) (run.Runner, string) {
+	t.Helper()
+	proxy := tempProxy(t)
+	writeProxyMod(t, proxy, "example.com/foo", "module example.com/foo\n\ngo 1.22\n\nrequire example.com/bar v1.0.0\n", "v1.2.3")
+	writeProxy(t, proxy, "example.com/bar", "v0.9.0", "v1.0.0", "v1.1.0")
+	runner := proxyRunner(t, proxy)
+
+	modroot := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/app\n\ngo 1.22\n\nrequire example.com/foo v1.2.3\n",
+		"main.go": "package main\n\nimport _ \"example.com/foo\"\n\nfunc main() {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(modroot, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return runner, modroot
+}
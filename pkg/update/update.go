@@ -2,17 +2,19 @@
 package update
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path"
 	"path/filepath"
-	"runtime"
 	"sort"
 	"strings"
 
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
 	"golang.org/x/mod/semver"
 
 	"github.com/chainguard-dev/gobump/pkg/run"
@@ -34,19 +36,35 @@ func ParseGoModfile(path string) (*modfile.File, []byte, error) {
 	return mod, content, nil
 }
 
-func checkPackageValues(pkgVersions map[string]*types.Package, modFile *modfile.File) error {
+func checkPackageValues(pkgVersions map[string]*types.Package, modFile *modfile.File, report map[string]*types.PackageResult) error {
 	if _, ok := pkgVersions[modFile.Module.Mod.Path]; ok {
-		return fmt.Errorf("bumping the main module is not allowed %q", modFile.Module.Mod.Path)
+		return &MainModuleError{Module: modFile.Module.Mod.Path}
 	}
 	type pkgVersion struct {
 		ReqVersion, AvailableVersion string
 	}
 	warnPkgVer := make(map[string]pkgVersion)
+	dirReplaces := make(map[string]string)
 
 	// Detect if the list of packages contain any replace statement for the package, if so we might drop that replace with a new one.
 	for _, replace := range modFile.Replace {
 		if replace != nil {
-			if _, ok := pkgVersions[replace.New.Path]; ok {
+			// A directory replace has no version to bump, the build would ignore the requested one.
+			if replace.New.Version == "" {
+				if pkg, ok := pkgVersions[replace.Old.Path]; ok && !pkg.Replace {
+					dirReplaces[replace.Old.Path] = replace.New.Path
+				}
+				continue
+			}
+			if pkg, ok := pkgVersions[replace.New.Path]; ok {
+				// A package targeting a version-specific replace leaves the other versions alone,
+				// otherwise it targets the replace the build uses.
+				if pkg.OldVersion != "" && replace.Old.Version != pkg.OldVersion {
+					continue
+				}
+				if pkg.OldVersion == "" && replace != appliedReplace(modFile.Replace, replace.Old.Path, requiredVersion(modFile, replace.Old.Path)) {
+					continue
+				}
 				// pkg is already been replaced
 				pkgVersions[replace.New.Path].Replace = true
 				// This happens when we found a replace in the go mod for a dependency that we defined in deps.
@@ -54,8 +72,12 @@ func checkPackageValues(pkgVersions map[string]*types.Package, modFile *modfile.
 				if pkgVersions[replace.New.Path].OldName == "" {
 					pkgVersions[replace.New.Path].OldName = replace.Old.Path
 				}
+				// Keep the replace version-specific, so it isn't collapsed with the replaces of the other versions.
+				if pkg.OldVersion == "" && replace.Old.Path == pkg.OldName {
+					pkg.OldVersion = replace.Old.Version
+				}
 				if semver.IsValid(pkgVersions[replace.New.Path].Version) {
-					if !pkgVersions[replace.New.Path].Force && semver.Compare(replace.New.Version, pkgVersions[replace.New.Path].Version) > 0 {
+					if !pkgVersions[replace.New.Path].AllowsDowngrade() && semver.Compare(replace.New.Version, pkgVersions[replace.New.Path].Version) > 0 {
 						warnPkgVer[replace.New.Path] = pkgVersion{
 							ReqVersion:       pkgVersions[replace.New.Path].Version,
 							AvailableVersion: replace.New.Version,
@@ -63,7 +85,7 @@ func checkPackageValues(pkgVersions map[string]*types.Package, modFile *modfile.
 						continue
 					}
 				} else {
-					fmt.Printf("Requesting pin to %s.\n This is not a valid SemVer, so skipping version check.\n", pkgVersions[replace.New.Path].Version)
+					warnNonSemver(report[replace.New.Path], pkgVersions[replace.New.Path].Version)
 				}
 			}
 		}
@@ -77,7 +99,7 @@ func checkPackageValues(pkgVersions map[string]*types.Package, modFile *modfile.
 				// Sometimes we request to pin to a specific commit.
 				// In that case, skip the compare check.
 				if semver.IsValid(pkgVersions[require.Mod.Path].Version) {
-					if !pkgVersions[require.Mod.Path].Force && semver.Compare(require.Mod.Version, pkgVersions[require.Mod.Path].Version) > 0 {
+					if !pkgVersions[require.Mod.Path].AllowsDowngrade() && semver.Compare(require.Mod.Version, pkgVersions[require.Mod.Path].Version) > 0 {
 						// Track the highest known current version for this package across multiple require entries
 						if existingPkg, exists := warnPkgVer[require.Mod.Path]; exists {
 							if semver.Compare(require.Mod.Version, existingPkg.AvailableVersion) > 0 {
@@ -95,126 +117,489 @@ func checkPackageValues(pkgVersions map[string]*types.Package, modFile *modfile.
 						continue
 					}
 				} else {
-					fmt.Printf("Requesting pin to %s.\n This is not a valid SemVer, so skipping version check.\n", pkgVersions[require.Mod.Path].Version)
+					warnNonSemver(report[require.Mod.Path], pkgVersions[require.Mod.Path].Version)
 				}
 			}
 		}
 	}
 
+	for pkg, dir := range dirReplaces {
+		msg := fmt.Sprintf("module is replaced by the directory %q", dir)
+		log.Printf("Warning: package %s: %s, skipping", pkg, msg)
+		if r, ok := report[pkg]; ok {
+			r.Skipped = true
+			r.SkipReason = types.SkipReasonDirectoryReplace
+			r.Message = msg
+		}
+		delete(pkgVersions, pkg)
+	}
+
 	for pkg, ver := range warnPkgVer {
-		log.Printf("Warning: package %s: requested version %q is older than current version %q, skipping", pkg, ver.ReqVersion, ver.AvailableVersion)
+		msg := fmt.Sprintf("requested version %q is older than current version %q", ver.ReqVersion, ver.AvailableVersion)
+		log.Printf("Warning: package %s: %s, skipping", pkg, msg)
+		if r, ok := report[pkg]; ok {
+			r.Skipped = true
+			r.SkipReason = types.SkipReasonDowngrade
+			r.Message = msg
+		}
 		delete(pkgVersions, pkg)
 	}
 
 	return nil
 }
 
+// warnNonSemver logs, and records in the package report, that a pin can't be version checked.
+func warnNonSemver(r *types.PackageResult, version string) {
+	log.Printf("Requesting pin to %s.\n This is not a valid SemVer, so skipping version check.\n", version)
+	if r != nil {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("requested version %q is not a valid SemVer, version check skipped", version))
+	}
+}
+
 // DoUpdate performs the actual update of Go module dependencies.
+// When cfg.DryRun is set the update runs against a scratch copy of the modroot instead.
 func DoUpdate(pkgVersions map[string]*types.Package, cfg *types.Config) (*modfile.File, error) {
+	result, err := DoUpdateWithResult(pkgVersions, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return result.ModFile, nil
+}
+
+// DoUpdateWithResult is like DoUpdate, but returns a report of what happened to every requested package.
+func DoUpdateWithResult(pkgVersions map[string]*types.Package, cfg *types.Config) (*types.Result, error) {
+	return DoUpdateContext(context.Background(), pkgVersions, cfg)
+}
+
+// DoUpdateContext is like DoUpdateWithResult, but stops the running go command when ctx is done.
+// A canceled or timed out update restores the module files to their original content.
+func DoUpdateContext(ctx context.Context, pkgVersions map[string]*types.Package, cfg *types.Config) (*types.Result, error) {
+	if cfg.DryRun {
+		return doDryRun(ctx, pkgVersions, cfg)
+	}
+
+	var snap *snapshot
+	if mayRollBack(ctx, cfg) {
+		var err error
+		if snap, err = takeSnapshot(snapshotPaths(cfg.Runner, cfg.Modroot, cfg.ForceWork)...); err != nil {
+			return nil, fmt.Errorf("failed to snapshot the module before the update: %w", err)
+		}
+	}
+
+	result, err := doUpdate(ctx, pkgVersions, cfg)
+	if err != nil && snap != nil && (cfg.Transactional || isCanceled(err)) {
+		log.Println("Update failed, rolling back the module ...")
+		if rerr := snap.restore(); rerr != nil {
+			return nil, fmt.Errorf("%w (rolling back the module also failed: %v)", err, rerr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// mayRollBack tells whether a failed update with cfg may be rolled back, so the module
+// must be snapshotted before it starts.
+func mayRollBack(ctx context.Context, cfg *types.Config) bool {
+	return cfg.Transactional || ctx.Done() != nil || cfg.Timeouts != (types.Timeouts{})
+}
+
+// isCanceled tells whether err comes from a canceled or timed out context.
+func isCanceled(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func doUpdate(ctx context.Context, pkgVersions map[string]*types.Package, cfg *types.Config) (*types.Result, error) {
 	var err error
-	goVersion := cfg.GoVersion
-	if goVersion == "" {
-		if goVersion, err = getGoVersionFromEnvironment(); err != nil {
-			return nil, fmt.Errorf("failed to get the Go version from the local system: %v", err)
+	if cfg.MaxGoVersion != "" {
+		if err := validateMaxGoVersion(cfg.MaxGoVersion, cfg.Directives); err != nil {
+			return nil, err
+		}
+	}
+	// Keep the original go.mod, before any go command, to report the changes of the whole update.
+	modpath := path.Join(cfg.Modroot, "go.mod")
+	origModFile, origContent, err := ParseGoModfile(modpath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the go mod file with error: %w", err)
+	}
+
+	runner := withTimeouts(cfg.Runner, cfg.Timeouts)
+	// The go version is only needed to tidy, or to keep go.work in line with the module.
+	var goVersion string
+	var goVersionSource types.GoVersionSource
+	if cfg.Tidy || cfg.ForceWork || run.FindGoWork(runner, cfg.Modroot) != "" {
+		if goVersion, goVersionSource, err = detectGoVersion(ctx, runner, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.MaxGoVersion != "" && cfg.Tidy {
+		if err := validateTidyGoVersion(cfg.MaxGoVersion, goVersion, origModFile); err != nil {
+			return nil, err
 		}
 	}
 
 	// Update go.work version FIRST before ANY go commands to avoid version mismatch errors
 	// This must happen even before the initial tidy
-	if err := run.UpdateGoWorkVersion(cfg.Modroot, cfg.ForceWork, goVersion); err != nil {
+	if err := run.UpdateGoWorkVersion(ctx, runner, cfg.Modroot, cfg.ForceWork, goVersion); err != nil {
 		log.Printf("Warning: failed to update go.work version: %v", err)
 	}
 
 	// Run go mod tidy before
 	if cfg.Tidy && !cfg.TidySkipInitial {
-		output, err := run.GoModTidy(cfg.Modroot, goVersion, cfg.TidyCompat)
+		output, err := run.GoModTidy(ctx, runner, cfg.Modroot, goVersion, cfg.TidyCompat)
 		if err != nil {
-			return nil, fmt.Errorf("failed to run 'go mod tidy': %v with output: %v", err, output)
+			return nil, fmt.Errorf("failed to run 'go mod tidy': %w with output: %v", err, output)
 		}
 	}
 
 	// Read the entire go.mod one more time into memory and check that all the version constraints are met.
-	modpath := path.Join(cfg.Modroot, "go.mod")
 	modFile, content, err := ParseGoModfile(modpath)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse the go mod file with error: %v", err)
+		return nil, fmt.Errorf("unable to parse the go mod file with error: %w", err)
+	}
+
+	// Resolve the version queries, and the latest-patch-above versions, before any version check.
+	pkgVersions, queries, err := resolveQueries(ctx, runner, cfg.Modroot, modFile, pkgVersions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep track of every requested package, checkPackageValues drops the skipped ones from pkgVersions.
+	requested := orderPkgVersionsMap(pkgVersions)
+	requestedPkgs := make([]*types.Package, 0, len(requested))
+	report := make(map[string]*types.PackageResult, len(requested))
+	for _, k := range requested {
+		requestedPkgs = append(requestedPkgs, pkgVersions[k])
+		report[k] = &types.PackageResult{
+			Name:             pkgVersions[k].Name,
+			RequestedVersion: pkgVersions[k].Version,
+			Query:            queries[k],
+			Mode:             pkgVersions[k].Mode,
+			Before:           getPackageVersion(modFile, pkgVersions[k]),
+		}
 	}
 
 	// Detect require/replace modules and validate the version values
-	err = checkPackageValues(pkgVersions, modFile)
+	err = checkPackageValues(pkgVersions, modFile, report)
+	if err != nil {
+		return nil, err
+	}
+
+	// The packages missing from go.mod must at least be in the module graph.
+	transitive, err := checkTransitive(ctx, runner, cfg.Modroot, pkgVersions, report)
 	if err != nil {
 		return nil, err
 	}
 
 	depsBumpOrdered := orderPkgVersionsMap(pkgVersions)
 
-	// Replace the packages first.
-	for _, k := range depsBumpOrdered {
-		pkg := pkgVersions[k]
-		if pkg.Replace {
-			log.Printf("Update package: %s\n", k)
-			log.Println("Running go mod edit replace ...")
-			if output, err := run.GoModEditReplaceModule(pkg.OldName, pkg.Name, pkg.Version, cfg.Modroot); err != nil {
-				return nil, fmt.Errorf("failed to run 'go mod edit -replace': %v for package %s/%s@%s with output: %v", err, pkg.OldName, pkg.Name, pkg.Version, output)
-			}
+	modfileBackend := cfg.EditBackend == types.EditBackendModfile
+	if modfileBackend {
+		// Apply the replaces and set the requires in one go, 'go get' below settles the module graph.
+		log.Println("Editing go.mod in memory ...")
+		if err := applyModfileEdits(modpath, content, pkgVersions, depsBumpOrdered); err != nil {
+			return nil, fmt.Errorf("failed to edit the go mod file: %w", err)
 		}
-	}
-	// Bump the require or new get packages in the specified order.
-	for _, k := range depsBumpOrdered {
-		pkg := pkgVersions[k]
-		// Skip the replace that have been updated above
-		if !pkg.Replace {
-			log.Printf("Update package: %s\n", k)
-			if pkg.Require {
-				log.Println("Running go mod edit -droprequire ...")
-				if output, err := run.GoModEditDropRequireModule(pkg.Name, cfg.Modroot); err != nil {
-					return nil, fmt.Errorf("failed to run 'go mod edit -droprequire': %v with output: %v", err, output)
+	} else {
+		// Replace the packages first.
+		for _, k := range depsBumpOrdered {
+			pkg := pkgVersions[k]
+			if pkg.Replace {
+				log.Printf("Update package: %s\n", k)
+				log.Println("Running go mod edit replace ...")
+				if output, err := run.GoModEditReplaceModule(ctx, runner, pkg.OldModule(), pkg.Name, pkg.Version, cfg.Modroot); err != nil {
+					return nil, fmt.Errorf("failed to run 'go mod edit -replace': %w for package %s/%s@%s with output: %v", err, pkg.OldModule(), pkg.Name, pkg.Version, output)
 				}
 			}
-			log.Println("Running go get ...")
-			if output, err := run.GoGetModule(pkg.Name, pkg.Version, cfg.Modroot); err != nil {
-				return nil, fmt.Errorf("failed to run 'go get': %v with output: %v", err, output)
-			}
 		}
 	}
+	// Bump the require or new get packages.
+	getMode := types.GetModeOrdered
+	if cfg.BatchGet {
+		getMode, err = getPackagesBatched(ctx, runner, cfg.Modroot, pkgVersions, depsBumpOrdered, !modfileBackend)
+	} else {
+		err = getPackagesOrdered(ctx, runner, cfg.Modroot, pkgVersions, depsBumpOrdered, !modfileBackend)
+	}
+	if err != nil {
+		return nil, err
+	}
 
 	// Run go mod tidy
 	if cfg.Tidy {
-		output, err := run.GoModTidy(cfg.Modroot, goVersion, cfg.TidyCompat)
+		// 'go get' may have raised the go line, which the file sources follow.
+		if goVersion, goVersionSource, err = detectGoVersion(ctx, runner, cfg); err != nil {
+			return nil, err
+		}
+		output, err := run.GoModTidy(ctx, runner, cfg.Modroot, goVersion, cfg.TidyCompat)
 		if err != nil {
-			return nil, fmt.Errorf("failed to run 'go mod tidy': %v with output: %v", err, output)
+			return nil, fmt.Errorf("failed to run 'go mod tidy': %w with output: %v", err, output)
+		}
+	}
+
+	// Tidy drops the transitive packages the build takes no package from, keep their bump.
+	if cfg.Tidy && len(transitive) > 0 {
+		if err := keepTransitive(modpath, pkgVersions, transitive); err != nil {
+			return nil, fmt.Errorf("failed to keep the transitive packages: %w", err)
+		}
+	}
+
+	if cfg.Annotate {
+		if err := annotate(modpath, pkgVersions); err != nil {
+			return nil, fmt.Errorf("failed to annotate the go mod file: %w", err)
 		}
 	}
 
+	// Set the go and toolchain lines last, tidy would otherwise rewrite the go line.
+	directives, err := applyDirectives(modpath, run.FindGoWork(runner, cfg.Modroot), cfg.Directives)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update the go and toolchain directives: %w", err)
+	}
+
 	// Read the entire go.mod one more time into memory and check that all the version constraints are met.
 	newModFile, newContent, err := ParseGoModfile(modpath)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse the go mod file with error: %v", err)
+		return nil, fmt.Errorf("unable to parse the go mod file with error: %w", err)
 	}
 	for _, pkg := range pkgVersions {
-		verStr := getVersion(newModFile, pkg.Name)
-		if verStr != "" && semver.Compare(verStr, pkg.Version) < 0 {
-			return nil, fmt.Errorf("package %s with %s is less than the desired version %s", pkg.Name, verStr, pkg.Version)
-		}
-		if verStr == "" {
-			return nil, fmt.Errorf("package %s was not found on the go.mod file. Please remove the package or add it to the list of 'replaces'", pkg.Name)
+		if err := verifyPackage(newModFile, pkg); err != nil {
+			return nil, err
 		}
 	}
+	collateral := collateralChanges(modFile, newModFile, report)
 
-	if cfg.ShowDiff {
-		if diff := cmp.Diff(string(content), string(newContent)); diff != "" {
-			fmt.Println(diff)
+	if cfg.MaxGoVersion != "" {
+		bumped := make(map[string]string, len(report)+len(collateral))
+		for _, r := range report {
+			if after := getVersion(newModFile, r.Name); after != "" && after != r.Before {
+				bumped[r.Name] = after
+			}
+		}
+		for _, c := range collateral {
+			if c.After != "" {
+				bumped[c.Path] = c.After
+			}
+		}
+		if err := checkMaxGoVersion(ctx, runner, cfg.Modroot, cfg.MaxGoVersion, origModFile, newModFile, bumped); err != nil {
+			return nil, err
 		}
 	}
 
+	diff := cmp.Diff(string(origContent), string(newContent))
+	if cfg.ShowDiff && diff != "" {
+		fmt.Println(diff)
+	}
+
 	if _, err := os.Stat(path.Join(cfg.Modroot, "vendor")); err == nil {
-		output, err := run.GoVendor(cfg.Modroot, cfg.ForceWork)
+		output, err := run.GoVendor(ctx, runner, cfg.Modroot, cfg.ForceWork)
 		if err != nil {
-			return nil, fmt.Errorf("failed to run 'go vendor': %v with output: %v", err, output)
+			return nil, fmt.Errorf("failed to run 'go vendor': %w with output: %v", err, output)
+		}
+	}
+
+	result := &types.Result{
+		Modroot:         cfg.Modroot,
+		Packages:        make([]types.PackageResult, 0, len(requested)),
+		Changed:         diff != "",
+		GetMode:         getMode,
+		GoVersion:       goVersion,
+		GoVersionSource: goVersionSource,
+		Directives:      directives,
+		Diff:            diff,
+		ModFile:         newModFile,
+	}
+	for i, k := range requested {
+		r := report[k]
+		r.OldName = requestedPkgs[i].OldName
+		r.OldVersion = requestedPkgs[i].OldVersion
+		r.Kind = types.KindRequire
+		if requestedPkgs[i].Replace {
+			r.Kind = types.KindReplace
 		}
+		r.After = getPackageVersion(newModFile, requestedPkgs[i])
+		result.Packages = append(result.Packages, *r)
 	}
+	result.Collateral = collateral
 
-	return newModFile, nil
+	return result, nil
+}
+
+// appliedReplace returns the replace of oldPath the build uses when oldPath is at the required
+// version: the one of that version, or else the one of all its versions. Without a required
+// version, the only replace of oldPath is the one used. It returns nil when it can't tell.
+func appliedReplace(replaces []*modfile.Replace, oldPath, required string) *modfile.Replace {
+	var wildcard, only *modfile.Replace
+	n := 0
+	for _, r := range replaces {
+		if r.Old.Path != oldPath {
+			continue
+		}
+		if required != "" && r.Old.Version == required {
+			return r
+		}
+		if r.Old.Version == "" {
+			wildcard = r
+		}
+		only = r
+		n++
+	}
+	if wildcard != nil {
+		return wildcard
+	}
+	if n == 1 && required == "" {
+		return only
+	}
+	return nil
+}
+
+// requiredVersion returns the version of path that modFile requires, empty if it doesn't.
+func requiredVersion(modFile *modfile.File, path string) string {
+	for _, req := range modFile.Require {
+		if req.Mod.Path == path {
+			return req.Mod.Version
+		}
+	}
+	return ""
+}
+
+// getPackagesOrdered bumps the requires, or gets the new packages, one 'go get' at a time in the specified order.
+func getPackagesOrdered(ctx context.Context, runner run.Runner, modroot string, pkgVersions map[string]*types.Package, order []string, setRequires bool) error {
+	for _, k := range order {
+		pkg := pkgVersions[k]
+		// Skip the replaces, they are already updated
+		if pkg.Replace {
+			continue
+		}
+		log.Printf("Update package: %s\n", k)
+		if pkg.Require && setRequires {
+			if err := setRequire(ctx, runner, modroot, pkg); err != nil {
+				return err
+			}
+		}
+		log.Println("Running go get ...")
+		if output, err := run.GoGetModule(ctx, runner, pkg.Name, pkg.Version, modroot); err != nil {
+			return fmt.Errorf("failed to run 'go get': %w with output: %v", err, output)
+		}
+	}
+	return nil
+}
+
+// setRequire points the require of pkg at its requested version in place, so that 'go get' keeps the
+// line in its block along with its comments and indirect marker. A version go.mod can't hold as is
+// drops the require instead and 'go get' adds it back.
+func setRequire(ctx context.Context, runner run.Runner, modroot string, pkg *types.Package) error {
+	if !canSetRequire(pkg) {
+		log.Printf("Running go mod edit -droprequire %s ...\n", pkg.Name)
+		if output, err := run.GoModEditDropRequireModule(ctx, runner, pkg.Name, modroot); err != nil {
+			return fmt.Errorf("failed to run 'go mod edit -droprequire': %w with output: %v", err, output)
+		}
+		return nil
+	}
+	log.Printf("Running go mod edit -require %s@%s ...\n", pkg.Name, pkg.Version)
+	if output, err := run.GoModEditRequireModule(ctx, runner, pkg.Name, pkg.Version, modroot); err != nil {
+		return fmt.Errorf("failed to run 'go mod edit -require': %w with output: %v", err, output)
+	}
+	return nil
+}
+
+// canSetRequire tells whether the requested version of pkg can be written to go.mod as is. The ones
+// 'go get' resolves first, such as a branch name, v1.2 or v3.0.0 for a module without a /v3
+// suffix, can't.
+func canSetRequire(pkg *types.Package) bool {
+	return module.Check(pkg.Name, pkg.Version) == nil
+}
+
+// getPackagesBatched bumps every require, or gets every new package, with a single 'go get' so the
+// resolver sees the whole request at once. If that fails, go.mod and go.sum are restored and the
+// packages are fetched again with getPackagesOrdered. It returns the mode that was finally used.
+func getPackagesBatched(ctx context.Context, runner run.Runner, modroot string, pkgVersions map[string]*types.Package, order []string, setRequires bool) (types.GetMode, error) {
+	var modules []string
+	for _, k := range order {
+		if pkg := pkgVersions[k]; !pkg.Replace {
+			modules = append(modules, fmt.Sprintf("%s@%s", pkg.Name, pkg.Version))
+		}
+	}
+	if len(modules) == 0 {
+		return types.GetModeBatched, nil
+	}
+
+	snap, err := takeSnapshot(filepath.Join(modroot, "go.mod"), filepath.Join(modroot, "go.sum"))
+	if err != nil {
+		return "", fmt.Errorf("failed to snapshot the module before the batched go get: %w", err)
+	}
+	if setRequires {
+		for _, k := range order {
+			if pkg := pkgVersions[k]; !pkg.Replace && pkg.Require {
+				if err := setRequire(ctx, runner, modroot, pkg); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+
+	log.Printf("Running go get for %d packages ...\n", len(modules))
+	output, err := run.GoGetModules(ctx, runner, modroot, modules...)
+	if err == nil {
+		return types.GetModeBatched, nil
+	}
+	if isCanceled(err) {
+		return "", fmt.Errorf("failed to run 'go get': %w with output: %v", err, output)
+	}
+
+	log.Printf("Warning: batched go get failed, falling back to one go get per package: %v with output: %v", err, output)
+	if err := snap.restore(); err != nil {
+		return "", fmt.Errorf("failed to restore the module after the batched go get: %w", err)
+	}
+	return types.GetModeOrderedFallback, getPackagesOrdered(ctx, runner, modroot, pkgVersions, order, setRequires)
+}
+
+// collateralChanges lists the required modules that moved between before and after without being requested.
+func collateralChanges(before, after *modfile.File, requested map[string]*types.PackageResult) []types.ModuleChange {
+	versions := func(f *modfile.File) map[string]string {
+		m := make(map[string]string, len(f.Require))
+		for _, req := range f.Require {
+			m[req.Mod.Path] = req.Mod.Version
+		}
+		return m
+	}
+	beforeVersions, afterVersions := versions(before), versions(after)
+
+	paths := make(map[string]struct{}, len(beforeVersions)+len(afterVersions))
+	for p := range beforeVersions {
+		paths[p] = struct{}{}
+	}
+	for p := range afterVersions {
+		paths[p] = struct{}{}
+	}
+
+	var changes []types.ModuleChange
+	for p := range paths {
+		if _, ok := requested[p]; ok {
+			continue
+		}
+		if beforeVersions[p] != afterVersions[p] {
+			changes = append(changes, types.ModuleChange{Path: p, Before: beforeVersions[p], After: afterVersions[p]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// verifyPackage checks that modFile satisfies the version requested for pkg, according to its mode.
+func verifyPackage(modFile *modfile.File, pkg *types.Package) error {
+	verStr := getPackageVersion(modFile, pkg)
+	if verStr != "" && pkg.Mode == types.VersionModeExact && verStr != pkg.Version {
+		return &NotExactError{Package: pkg.Name, Version: verStr, Requested: pkg.Version}
+	}
+	if verStr != "" && semver.Compare(verStr, pkg.Version) < 0 {
+		return &BelowRequestedError{Package: pkg.Name, Version: verStr, Requested: pkg.Version}
+	}
+	if verStr == "" {
+		return &NotFoundError{Package: pkg.Name}
+	}
+	return nil
 }
 
 func orderPkgVersionsMap(pkgVersions map[string]*types.Package) []string {
@@ -248,11 +633,25 @@ func getVersion(modFile *modfile.File, packageName string) string {
 	return ""
 }
 
-// getGoVersionFromEnvironment returns the Go version from the local environment.
-func getGoVersionFromEnvironment() (string, error) {
-	// Use runtime.Version() to get the Go version without exec.Command
-	versionOutput := fmt.Sprintf("go version %s", runtime.Version())
-	return parseGoVersionString(versionOutput)
+// getPackageVersion is like getVersion, but only looks at the replace of pkg.OldVersion when the package targets one.
+func getPackageVersion(modFile *modfile.File, pkg *types.Package) string {
+	if pkg.OldVersion != "" {
+		for _, replace := range modFile.Replace {
+			if replace.New.Path == pkg.Name && replace.Old.Version == pkg.OldVersion {
+				return replace.New.Version
+			}
+		}
+	}
+	return getVersion(modFile, pkg.Name)
+}
+
+// getGoVersionFromEnvironment returns the Go version of the go command run in modroot.
+func getGoVersionFromEnvironment(ctx context.Context, runner run.Runner, modroot string) (string, error) {
+	goVersion, err := run.GoEnvVersion(ctx, runner, modroot)
+	if err != nil {
+		return "", err
+	}
+	return parseGoVersionString(fmt.Sprintf("go version %s", goVersion))
 }
 
 // parseGoVersionString parses the output of `go version` command and extracts the Go version.
@@ -1,13 +1,20 @@
 package update
 
 import (
+	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/mod/modfile"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
 	"github.com/chainguard-dev/gobump/pkg/types"
 )
 
@@ -357,7 +364,7 @@ func TestGoModTidySkipInitial(t *testing.T) {
 			},
 			tidySkipInitial: false,
 			wantError:       true,
-			errMsgContains:  "failed to run",
+			errMsgContains:  "nor in its module graph",
 		},
 		{
 			name: "skip initial tidy",
@@ -540,6 +547,180 @@ func TestReplaces(t *testing.T) {
 	}
 }
 
+func TestVersionSpecificReplaces(t *testing.T) {
+	const gomod = `module example.com/app
+
+go 1.22
+
+require example.com/foo v1.2.3
+
+replace (
+	example.com/foo v1.0.0 => example.com/fork v1.0.1
+	example.com/foo v1.2.3 => example.com/fork v1.2.4
+)
+`
+	testCases := []struct {
+		name       string
+		oldVersion string
+		backend    types.EditBackend
+		want       map[string]string
+	}{
+		{
+			name:       "targeted version",
+			oldVersion: "v1.2.3",
+			want:       map[string]string{"v1.0.0": "v1.0.1", "v1.2.3": "v1.2.5"},
+		},
+		{
+			name:       "targeted version with the modfile backend",
+			oldVersion: "v1.2.3",
+			backend:    types.EditBackendModfile,
+			want:       map[string]string{"v1.0.0": "v1.0.1", "v1.2.3": "v1.2.5"},
+		},
+		{
+			name: "replace of the required version",
+			want: map[string]string{"v1.0.0": "v1.0.1", "v1.2.3": "v1.2.5"},
+		},
+		{
+			name:    "replace of the required version with the modfile backend",
+			backend: types.EditBackendModfile,
+			want:    map[string]string{"v1.0.0": "v1.0.1", "v1.2.3": "v1.2.5"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpdir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmpdir, "go.mod"), []byte(gomod), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			pkgVersions := map[string]*types.Package{
+				"example.com/fork": {Name: "example.com/fork", Version: "v1.2.5", OldVersion: tc.oldVersion},
+			}
+			result, err := DoUpdateWithResult(pkgVersions, &types.Config{Modroot: tmpdir, EditBackend: tc.backend})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := map[string]string{}
+			for _, r := range result.ModFile.Replace {
+				got[r.Old.Version] = r.New.Version
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("replaces by old version (-want +got)\n%s", diff)
+			}
+			if p := result.Packages[0]; p.After != "v1.2.5" || p.OldVersion == "" {
+				t.Errorf("package result = %+v, want the version-specific replace bumped to v1.2.5", p)
+			}
+		})
+	}
+}
+
+func TestAppliedReplace(t *testing.T) {
+	const gomod = `module example.com/app
+
+go 1.22
+
+replace (
+	example.com/foo v1.0.0 => example.com/fork v1.0.1
+	example.com/foo v1.2.3 => example.com/fork v1.2.4
+	example.com/foo => example.com/fork v1.3.0
+	example.com/bar v1.0.0 => example.com/fork v1.0.1
+)
+`
+	modFile, err := modfile.Parse("go.mod", []byte(gomod), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name     string
+		oldPath  string
+		required string
+		want     string
+	}{
+		{name: "required version", oldPath: "example.com/foo", required: "v1.2.3", want: "example.com/fork v1.2.4"},
+		{name: "wildcard for another version", oldPath: "example.com/foo", required: "v1.1.0", want: "example.com/fork v1.3.0"},
+		{name: "wildcard when not required", oldPath: "example.com/foo", want: "example.com/fork v1.3.0"},
+		{name: "only replace when not required", oldPath: "example.com/bar", want: "example.com/fork v1.0.1"},
+		{name: "no replace of the required version", oldPath: "example.com/bar", required: "v1.2.0"},
+		{name: "not replaced", oldPath: "example.com/baz"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			if r := appliedReplace(modFile.Replace, tc.oldPath, tc.required); r != nil {
+				got = r.New.Path + " " + r.New.Version
+			}
+			if got != tc.want {
+				t.Errorf("appliedReplace(%s, %q) = %q, want %q", tc.oldPath, tc.required, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDiffFromOriginal(t *testing.T) {
+	proxy := tempProxy(t)
+	writeProxy(t, proxy, "example.com/foo", "v1.2.3", "v1.3.0")
+	writeProxy(t, proxy, "example.com/bar", "v1.0.0")
+	runner := proxyRunner(t, proxy)
+	modroot := t.TempDir()
+	gomod := "module example.com/app\n\ngo 1.22\n\nrequire (\n\texample.com/bar v1.0.0\n\texample.com/foo v1.2.3\n)\n"
+	if err := os.WriteFile(filepath.Join(modroot, "go.mod"), []byte(gomod), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	main := "package main\n\nimport _ \"example.com/foo\"\n\nfunc main() {}\n"
+	if err := os.WriteFile(filepath.Join(modroot, "main.go"), []byte(main), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgVersions := map[string]*types.Package{
+		"example.com/foo": {Name: "example.com/foo", Version: "v1.3.0"},
+	}
+	result, err := DoUpdateWithResult(pkgVersions, &types.Config{
+		Modroot:          modroot,
+		Runner:           runner,
+		Tidy:             true,
+		GoVersionSources: []types.GoVersionSource{types.GoVersionSourceGoMod},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The initial tidy drops example.com/bar, which is part of the change.
+	if !result.Changed || !strings.Contains(result.Diff, "example.com/bar v1.0.0") {
+		t.Errorf("diff does not drop example.com/bar:\n%s", result.Diff)
+	}
+}
+
+func TestDirectoryReplace(t *testing.T) {
+	tmpdir := t.TempDir()
+	gomod := "module example.com/app\n\ngo 1.22\n\nrequire example.com/foo v1.2.3\n\nreplace example.com/foo => ../fork\n"
+	if err := os.WriteFile(filepath.Join(tmpdir, "go.mod"), []byte(gomod), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgVersions := map[string]*types.Package{
+		"example.com/foo": {Name: "example.com/foo", Version: "v1.3.0"},
+	}
+	result, err := DoUpdateWithResult(pkgVersions, &types.Config{Modroot: tmpdir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.PackageResult{
+		Name:             "example.com/foo",
+		Kind:             types.KindRequire,
+		RequestedVersion: "v1.3.0",
+		Before:           "v1.2.3",
+		After:            "v1.2.3",
+		Skipped:          true,
+		SkipReason:       types.SkipReasonDirectoryReplace,
+		Message:          `module is replaced by the directory "../fork"`,
+	}
+	if diff := cmp.Diff([]types.PackageResult{want}, result.Packages); diff != "" {
+		t.Errorf("package results (-want +got)\n%s", diff)
+	}
+	if result.Changed {
+		t.Errorf("go.mod changed:\n%s", result.Diff)
+	}
+}
+
 func TestCommit(t *testing.T) {
 	// We use github.com/NVIDIA/go-nvml v0.11.7-0 in our go.mod
 	// That corresponds to 53c34bc04d66e9209eff8654bc70563cf380e214
@@ -612,6 +793,436 @@ func TestCommit(t *testing.T) {
 
 }
 
+func TestDryRun(t *testing.T) {
+	tmpdir := t.TempDir()
+	copyFile(t, "testdata/hello/go.mod", tmpdir)
+	copyFile(t, "testdata/hello/go.sum", tmpdir)
+	copyFile(t, "testdata/hello/main.go", tmpdir)
+
+	wantMod, err := os.ReadFile(filepath.Join(tmpdir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSum, err := os.ReadFile(filepath.Join(tmpdir, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkgVersions := map[string]*types.Package{
+		"github.com/sirupsen/logrus": {
+			Name:    "github.com/sirupsen/logrus",
+			Version: "v1.9.0",
+		},
+	}
+	modFile, err := DoUpdate(pkgVersions, &types.Config{Modroot: tmpdir, Tidy: true, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := getVersion(modFile, "github.com/sirupsen/logrus"); got != "v1.9.0" {
+		t.Errorf("expected planned version v1.9.0, got %s", got)
+	}
+
+	gotMod, err := os.ReadFile(filepath.Join(tmpdir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(gotMod) != string(wantMod) {
+		t.Errorf("dry run modified go.mod:\n%s", gotMod)
+	}
+	gotSum, err := os.ReadFile(filepath.Join(tmpdir, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(gotSum) != string(wantSum) {
+		t.Error("dry run modified go.sum")
+	}
+}
+
+func TestDryRunOutsideModroot(t *testing.T) {
+	proxy := tempProxy(t)
+	writeProxy(t, proxy, "example.com/foo", "v1.2.3", "v1.2.5")
+	root := t.TempDir()
+	modroot := filepath.Join(root, "app")
+	writeModule(t, modroot, "example.com/app", "example.com/foo v1.2.3", "example.com/bar v1.0.0")
+	writeModule(t, filepath.Join(root, "fork"), "example.com/bar")
+	modpath := filepath.Join(modroot, "go.mod")
+	f, err := os.OpenFile(modpath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("\nreplace example.com/bar => ../fork\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The go.work of GOWORK doesn't use the module, the dry run must neither use nor edit it.
+	workPath := filepath.Join(root, "go.work")
+	if err := os.WriteFile(workPath, []byte("go 1.21\n\nuse ./fork\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	wantMod, err := os.ReadFile(modpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkgVersions := map[string]*types.Package{
+		"example.com/foo": {Name: "example.com/foo", Version: "v1.2.5"},
+	}
+	result, err := DoUpdateWithResult(pkgVersions, &types.Config{
+		Modroot: modroot,
+		Runner:  proxyRunner(t, proxy, "GOWORK="+workPath),
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := getVersion(result.ModFile, "example.com/foo"); got != "v1.2.5" {
+		t.Errorf("expected planned version v1.2.5, got %s", got)
+	}
+	if len(result.ModFile.Replace) != 1 {
+		t.Fatalf("expected one replace, got %d", len(result.ModFile.Replace))
+	}
+	if got := result.ModFile.Replace[0].New.Path; got != "../fork" {
+		t.Errorf("expected the replace of example.com/bar by ../fork, got %s", got)
+	}
+
+	for path, want := range map[string]string{modpath: string(wantMod), workPath: "go 1.21\n\nuse ./fork\n"} {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("dry run modified %s:\n%s", path, got)
+		}
+	}
+}
+
+func TestUpdateResult(t *testing.T) {
+	tmpdir := t.TempDir()
+	copyFile(t, "testdata/aws-efs-csi-driver/go.mod", tmpdir)
+
+	pkgVersions := map[string]*types.Package{
+		"github.com/google/uuid": {
+			Name:    "github.com/google/uuid",
+			Version: "v1.4.0",
+			Index:   0,
+		},
+		"k8s.io/api": {
+			Name:    "k8s.io/api",
+			Version: "v0.20.0",
+			Index:   1,
+		},
+	}
+	result, err := DoUpdateWithResult(pkgVersions, &types.Config{Modroot: tmpdir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []types.PackageResult{{
+		Name:             "github.com/google/uuid",
+		Kind:             types.KindRequire,
+		RequestedVersion: "v1.4.0",
+		Before:           "v1.3.1",
+		After:            "v1.4.0",
+	}, {
+		Name:             "k8s.io/api",
+		OldName:          "k8s.io/api",
+		Kind:             types.KindReplace,
+		RequestedVersion: "v0.20.0",
+		Before:           "v0.26.10",
+		After:            "v0.26.10",
+		Skipped:          true,
+		SkipReason:       types.SkipReasonDowngrade,
+		Message:          `requested version "v0.20.0" is older than current version "v0.26.10"`,
+	}}
+	if diff := cmp.Diff(want, result.Packages); diff != "" {
+		t.Errorf("DoUpdateWithResult() packages (-want +got)\n%s", diff)
+	}
+	for _, c := range result.Collateral {
+		if c.Path == "github.com/google/uuid" || c.Path == "k8s.io/api" {
+			t.Errorf("requested package %s reported as collateral", c.Path)
+		}
+	}
+	if !strings.Contains(result.Diff, "github.com/google/uuid") {
+		t.Errorf("expected the diff to mention github.com/google/uuid, got:\n%s", result.Diff)
+	}
+}
+
+func TestTransactionalRollback(t *testing.T) {
+	tmpdir := t.TempDir()
+	copyFile(t, "testdata/aws-efs-csi-driver/go.mod", tmpdir)
+	want, err := os.ReadFile(filepath.Join(tmpdir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The replace succeeds, then 'go get' of a version that doesn't exist fails.
+	pkgVersions := map[string]*types.Package{
+		"github.com/fakefuzz": {
+			OldName: "github.com/google/gofuzz",
+			Name:    "github.com/fakefuzz",
+			Version: "v1.2.3",
+			Replace: true,
+			Index:   0,
+		},
+		"github.com/google/uuid": {
+			Name:    "github.com/google/uuid",
+			Version: "v1.4.0-doesnotexist",
+			Index:   1,
+		},
+	}
+	if _, err := DoUpdate(pkgVersions, &types.Config{Modroot: tmpdir, Transactional: true}); err == nil {
+		t.Fatal("expected DoUpdate to fail")
+	}
+
+	got, err := os.ReadFile(filepath.Join(tmpdir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(want), string(got)); diff != "" {
+		t.Errorf("go.mod was not rolled back (-want +got)\n%s", diff)
+	}
+	if _, err := os.Stat(filepath.Join(tmpdir, "go.sum")); !os.IsNotExist(err) {
+		t.Errorf("expected go.sum to not exist after the rollback, got err = %v", err)
+	}
+}
+
+// recordingRunner records every go invocation before delegating it to the exec runner.
+type recordingRunner struct {
+	run.ExecRunner
+	calls []string
+}
+
+func (r *recordingRunner) Run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	r.calls = append(r.calls, strings.Join(args, " "))
+	return r.ExecRunner.Run(ctx, dir, args...)
+}
+
+// recorder is a run.Runner that remembers the go invocations it ran.
+type recorder interface {
+	run.Runner
+	recorded() []string
+}
+
+func (r *recordingRunner) recorded() []string {
+	return r.calls
+}
+
+func TestUpdateRunner(t *testing.T) {
+	tmpdir := t.TempDir()
+	copyFile(t, "testdata/aws-efs-csi-driver/go.mod", tmpdir)
+
+	pkgVersions := map[string]*types.Package{
+		"github.com/google/uuid": {
+			Name:    "github.com/google/uuid",
+			Version: "v1.4.0",
+		},
+	}
+	runner := &recordingRunner{}
+	if _, err := DoUpdate(pkgVersions, &types.Config{Modroot: tmpdir, Runner: runner}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"mod edit -require github.com/google/uuid@v1.4.0",
+		"get github.com/google/uuid@v1.4.0",
+	}
+	if diff := cmp.Diff(want, runner.calls); diff != "" {
+		t.Errorf("go invocations (-want +got)\n%s", diff)
+	}
+}
+
+// hangingGetRunner runs every go command but 'go get', which blocks until ctx is done.
+type hangingGetRunner struct {
+	run.ExecRunner
+}
+
+func (r *hangingGetRunner) Run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	if args[0] == "get" {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return r.ExecRunner.Run(ctx, dir, args...)
+}
+
+func TestUpdateTimeoutRestoresModule(t *testing.T) {
+	tmpdir := t.TempDir()
+	copyFile(t, "testdata/aws-efs-csi-driver/go.mod", tmpdir)
+	want, err := os.ReadFile(filepath.Join(tmpdir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkgVersions := map[string]*types.Package{
+		"github.com/google/uuid": {
+			Name:    "github.com/google/uuid",
+			Version: "v1.4.0",
+		},
+	}
+	_, err = DoUpdateContext(context.Background(), pkgVersions, &types.Config{
+		Modroot:  tmpdir,
+		Runner:   &hangingGetRunner{},
+		Timeouts: types.Timeouts{Get: 100 * time.Millisecond},
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("DoUpdateContext() = %v, want an error wrapping %v", err, context.DeadlineExceeded)
+	}
+
+	got, err := os.ReadFile(filepath.Join(tmpdir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(want), string(got)); diff != "" {
+		t.Errorf("go.mod was not restored (-want +got)\n%s", diff)
+	}
+}
+
+// failingBatchRunner records every go invocation and fails any 'go get' of more than one module.
+type failingBatchRunner struct {
+	recordingRunner
+}
+
+func (r *failingBatchRunner) Run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	if args[0] == "get" && len(args) > 2 {
+		r.calls = append(r.calls, strings.Join(args, " "))
+		return []byte("batched go get failed"), errors.New("exit status 1")
+	}
+	return r.recordingRunner.Run(ctx, dir, args...)
+}
+
+func TestBatchGet(t *testing.T) {
+	const (
+		logrus = "github.com/sirupsen/logrus"
+		sys    = "golang.org/x/sys"
+		sysVer = "v0.0.0-20220715151400-c0bba94af5f8"
+	)
+	testCases := []struct {
+		name      string
+		runner    recorder
+		wantMode  types.GetMode
+		wantCalls []string
+	}{
+		{
+			name:     "batched",
+			runner:   &recordingRunner{},
+			wantMode: types.GetModeBatched,
+			wantCalls: []string{
+				"mod edit -require " + logrus + "@v1.9.0",
+				"mod edit -require " + sys + "@" + sysVer,
+				"get " + logrus + "@v1.9.0 " + sys + "@" + sysVer,
+			},
+		},
+		{
+			name:     "fallback to ordered",
+			runner:   &failingBatchRunner{},
+			wantMode: types.GetModeOrderedFallback,
+			wantCalls: []string{
+				"mod edit -require " + logrus + "@v1.9.0",
+				"mod edit -require " + sys + "@" + sysVer,
+				"get " + logrus + "@v1.9.0 " + sys + "@" + sysVer,
+				"mod edit -require " + logrus + "@v1.9.0",
+				"get " + logrus + "@v1.9.0",
+				"mod edit -require " + sys + "@" + sysVer,
+				"get " + sys + "@" + sysVer,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpdir := t.TempDir()
+			copyFile(t, "testdata/hello/go.mod", tmpdir)
+			copyFile(t, "testdata/hello/go.sum", tmpdir)
+			copyFile(t, "testdata/hello/main.go", tmpdir)
+
+			pkgVersions := map[string]*types.Package{
+				logrus: {Name: logrus, Version: "v1.9.0", Index: 0},
+				sys:    {Name: sys, Version: sysVer, Index: 1},
+			}
+			result, err := DoUpdateWithResult(pkgVersions, &types.Config{
+				Modroot:  tmpdir,
+				BatchGet: true,
+				Runner:   tc.runner,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.GetMode != tc.wantMode {
+				t.Errorf("get mode: got %q, want %q", result.GetMode, tc.wantMode)
+			}
+			if diff := cmp.Diff(tc.wantCalls, tc.runner.recorded()); diff != "" {
+				t.Errorf("go invocations (-want +got)\n%s", diff)
+			}
+			if got := getVersion(result.ModFile, logrus); got != "v1.9.0" {
+				t.Errorf("expected %s v1.9.0, got %s", logrus, got)
+			}
+		})
+	}
+}
+
+func TestUpdateErrorTypes(t *testing.T) {
+	t.Run("main module", func(t *testing.T) {
+		tmpdir := t.TempDir()
+		copyFile(t, "testdata/hello/go.mod", tmpdir)
+
+		pkgVersions := map[string]*types.Package{
+			"github.com/puerco/hello": {Name: "github.com/puerco/hello", Version: "v1.9.0"},
+		}
+		_, err := DoUpdate(pkgVersions, &types.Config{Modroot: tmpdir})
+		var mainErr *MainModuleError
+		if !errors.As(err, &mainErr) || mainErr.Module != "github.com/puerco/hello" {
+			t.Errorf("DoUpdate() = %v, want a *MainModuleError for github.com/puerco/hello", err)
+		}
+	})
+
+	t.Run("failed go command", func(t *testing.T) {
+		tmpdir := t.TempDir()
+		copyFile(t, "testdata/aws-efs-csi-driver/go.mod", tmpdir)
+
+		pkgVersions := map[string]*types.Package{
+			"github.com/google/uuid": {Name: "github.com/google/uuid", Version: "v1.4.0-doesnotexist"},
+		}
+		_, err := DoUpdate(pkgVersions, &types.Config{Modroot: tmpdir})
+		var cmdErr *run.CommandError
+		if !errors.As(err, &cmdErr) {
+			t.Fatalf("DoUpdate() = %v, want a *run.CommandError", err)
+		}
+		if want := []string{"get", "github.com/google/uuid@v1.4.0-doesnotexist"}; !reflect.DeepEqual(cmdErr.Args, want) {
+			t.Errorf("failed command args: got %q, want %q", cmdErr.Args, want)
+		}
+		if cmdErr.ExitCode == 0 || cmdErr.Output == "" {
+			t.Errorf("expected a non zero exit code and some output, got %d and %q", cmdErr.ExitCode, cmdErr.Output)
+		}
+	})
+
+	t.Run("not found and below requested", func(t *testing.T) {
+		modFile, _, err := ParseGoModfile("testdata/aws-efs-csi-driver/go.mod")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var notFound *NotFoundError
+		if err := verifyPackage(modFile, &types.Package{Name: "example.com/absent", Version: "v1.0.0"}); !errors.As(err, &notFound) {
+			t.Errorf("verifyPackage() = %v, want a *NotFoundError", err)
+		}
+		var below *BelowRequestedError
+		err = verifyPackage(modFile, &types.Package{Name: "github.com/google/uuid", Version: "v1.4.0"})
+		if !errors.As(err, &below) || below.Version != "v1.3.1" || below.Requested != "v1.4.0" {
+			t.Errorf("verifyPackage() = %v, want a *BelowRequestedError from v1.3.1 to v1.4.0", err)
+		}
+		var notExact *NotExactError
+		err = verifyPackage(modFile, &types.Package{Name: "github.com/google/uuid", Version: "v1.3.0", Mode: types.VersionModeExact})
+		if !errors.As(err, &notExact) || notExact.Version != "v1.3.1" || notExact.Requested != "v1.3.0" {
+			t.Errorf("verifyPackage() = %v, want a *NotExactError from v1.3.1 to v1.3.0", err)
+		}
+		var mismatch *QueryMismatchError
+		err = checkPackage(modFile, &types.Package{Name: "github.com/google/uuid", Version: "~v1.4.0"})
+		if !errors.As(err, &mismatch) || mismatch.Version != "v1.3.1" || mismatch.Query != "~v1.4.0" {
+			t.Errorf("checkPackage() = %v, want a *QueryMismatchError from v1.3.1 to ~v1.4.0", err)
+		}
+	})
+}
+
 func copyFile(t *testing.T, src, dst string) {
 	t.Helper()
 	_, err := exec.Command("cp", "-r", src, dst).Output()
@@ -0,0 +1,216 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// ParseGoWorkfile parses a go.work file from the specified path.
+func ParseGoWorkfile(path string) (*modfile.WorkFile, error) {
+	path = filepath.Clean(path)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return modfile.ParseWork(path, content, nil)
+}
+
+// DoUpdateWorkspace applies the bumps to the workspace cfg.Modroot belongs to. The packages replaced
+// by go.work are bumped through its replace directives, the other ones in every module of its use
+// directives that depends on them, like DoUpdateModules. 'go work sync' runs at the end so the
+// requirements of the modules are consistent with the workspace. A dry run updates a copy of the
+// directory of go.work instead. Like DoUpdateContext, a failed update restores go.work, go.work.sum
+// and the modules when cfg.Transactional is set or it was canceled or timed out.
+func DoUpdateWorkspace(ctx context.Context, pkgVersions map[string]*types.Package, cfg *types.Config) (*types.WorkspaceResult, error) {
+	runner := withTimeouts(cfg.Runner, cfg.Timeouts)
+	workPath := run.FindGoWork(runner, cfg.Modroot)
+	if workPath == "" {
+		return nil, fmt.Errorf("no go.work found for %q", cfg.Modroot)
+	}
+	workFile, err := ParseGoWorkfile(workPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the go work file with error: %w", err)
+	}
+	if cfg.DryRun {
+		return doWorkspaceDryRun(ctx, pkgVersions, cfg, workPath, workFile)
+	}
+	workdir := filepath.Dir(workPath)
+	modroots := make([]string, 0, len(workFile.Use))
+	for _, use := range workFile.Use {
+		dir := use.Path
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(workdir, dir)
+		}
+		modroots = append(modroots, dir)
+	}
+
+	var snap *snapshot
+	if mayRollBack(ctx, cfg) {
+		paths := []string{workPath, workPath + ".sum"}
+		for _, modroot := range modroots {
+			paths = append(paths, snapshotPaths(runner, modroot, false)...)
+		}
+		if snap, err = takeSnapshot(paths...); err != nil {
+			return nil, fmt.Errorf("failed to snapshot the workspace before the update: %w", err)
+		}
+	}
+
+	result, err := doUpdateWorkspace(ctx, runner, pkgVersions, cfg, workPath, workFile, modroots)
+	if err != nil && snap != nil && (cfg.Transactional || isCanceled(err)) {
+		log.Println("Update failed, rolling back the workspace ...")
+		if rerr := snap.restore(); rerr != nil {
+			return nil, fmt.Errorf("%w (rolling back the workspace also failed: %v)", err, rerr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func doUpdateWorkspace(ctx context.Context, runner run.Runner, pkgVersions map[string]*types.Package, cfg *types.Config, workPath string, workFile *modfile.WorkFile, modroots []string) (*types.WorkspaceResult, error) {
+	workdir := filepath.Dir(workPath)
+	result := &types.WorkspaceResult{GoWork: workPath}
+	// Every module gets its own copy of the packages, the replaced ones are only bumped in go.work.
+	modulePkgs := make(map[string]*types.Package, len(pkgVersions))
+	for k, pkg := range pkgVersions {
+		p := *pkg
+		modulePkgs[k] = &p
+	}
+	required, err := workspaceRequires(modroots)
+	if err != nil {
+		return nil, err
+	}
+	// Resolve the version queries of the packages go.work replaces against its replaces, like
+	// doUpdate does against go.mod. The packages left to the modules keep their queries.
+	workPkgs := map[string]*types.Package{}
+	for _, replace := range workFile.Replace {
+		if pkg, ok := modulePkgs[replace.New.Path]; ok && replace.New.Version != "" {
+			workPkgs[replace.New.Path] = pkg
+		}
+	}
+	workPkgs, queries, err := resolveQueries(ctx, runner, workdir, &modfile.File{Replace: workFile.Replace}, workPkgs)
+	if err != nil {
+		return nil, err
+	}
+	candidates := maps.Clone(modulePkgs)
+	maps.Copy(candidates, workPkgs)
+	replaces := checkWorkReplaces(candidates, workFile, required, queries, result)
+	for k := range modulePkgs {
+		if _, ok := candidates[k]; !ok {
+			delete(modulePkgs, k)
+		}
+	}
+	for _, k := range orderPkgVersionsMap(replaces) {
+		pkg := replaces[k]
+		log.Printf("Update workspace replace: %s\n", k)
+		if output, err := run.GoWorkEditReplaceModule(ctx, runner, pkg.OldModule(), pkg.Name, pkg.Version, workdir); err != nil {
+			return nil, fmt.Errorf("failed to run 'go work edit -replace': %w for package %s/%s@%s with output: %v", err, pkg.OldModule(), pkg.Name, pkg.Version, output)
+		}
+	}
+
+	modCfg := *cfg
+	modCfg.Modroots = modroots
+	if result.Modules, err = DoUpdateModules(ctx, modulePkgs, &modCfg); err != nil {
+		return nil, err
+	}
+
+	log.Println("Running go work sync ...")
+	if output, err := run.GoWorkSync(ctx, runner, workdir); err != nil {
+		return nil, fmt.Errorf("failed to run 'go work sync': %w with output: %v", err, output)
+	}
+
+	// The replaces of go.work, and the sync, may have moved the requirements of any module.
+	for _, m := range result.Modules {
+		if m.ModFile, _, err = ParseGoModfile(filepath.Join(m.Modroot, "go.mod")); err != nil {
+			return nil, fmt.Errorf("unable to parse the go mod file with error: %w", err)
+		}
+	}
+	if workFile, err = ParseGoWorkfile(workPath); err != nil {
+		return nil, fmt.Errorf("unable to parse the go work file with error: %w", err)
+	}
+	for i := range result.Replaces {
+		r := &result.Replaces[i]
+		for _, replace := range workFile.Replace {
+			if replace.New.Path == r.Name && replace.Old.Version == r.OldVersion {
+				r.After = replace.New.Version
+			}
+		}
+	}
+	return result, nil
+}
+
+// workspaceRequires returns the highest version of every module the modules in modroots require,
+// the one the workspace build selects at least.
+func workspaceRequires(modroots []string) (map[string]string, error) {
+	required := map[string]string{}
+	for _, modroot := range modroots {
+		modFile, _, err := ParseGoModfile(filepath.Join(modroot, "go.mod"))
+		if err != nil {
+			return nil, fmt.Errorf("module %s: unable to parse the go mod file with error: %w", modroot, err)
+		}
+		for _, req := range modFile.Require {
+			if semver.Compare(req.Mod.Version, required[req.Mod.Path]) > 0 {
+				required[req.Mod.Path] = req.Mod.Version
+			}
+		}
+	}
+	return required, nil
+}
+
+// checkWorkReplaces moves out of pkgVersions, and returns, the packages that go.work replaces.
+// Without an old version, a package targets the replace the workspace uses for the version of
+// the old module required by its modules. Like checkPackageValues does for go.mod, a package older
+// than the replacement in go.work is skipped unless it allows downgrades. Every moved package is
+// recorded in result, along with its original query in queries if any.
+func checkWorkReplaces(pkgVersions map[string]*types.Package, workFile *modfile.WorkFile, required, queries map[string]string, result *types.WorkspaceResult) map[string]*types.Package {
+	replaces := map[string]*types.Package{}
+	for _, replace := range workFile.Replace {
+		pkg, ok := pkgVersions[replace.New.Path]
+		if !ok || replace.New.Version == "" || (pkg.OldVersion != "" && replace.Old.Version != pkg.OldVersion) {
+			continue
+		}
+		if pkg.OldVersion == "" && replace != appliedReplace(workFile.Replace, replace.Old.Path, required[replace.Old.Path]) {
+			continue
+		}
+		delete(pkgVersions, replace.New.Path)
+		pkg.Replace = true
+		if pkg.OldName == "" {
+			pkg.OldName = replace.Old.Path
+		}
+		if pkg.OldVersion == "" && replace.Old.Path == pkg.OldName {
+			pkg.OldVersion = replace.Old.Version
+		}
+		r := types.PackageResult{
+			Name:             pkg.Name,
+			OldName:          pkg.OldName,
+			OldVersion:       pkg.OldVersion,
+			Kind:             types.KindReplace,
+			RequestedVersion: pkg.Version,
+			Query:            queries[pkg.Name],
+			Mode:             pkg.Mode,
+			Before:           replace.New.Version,
+			After:            replace.New.Version,
+		}
+		if semver.IsValid(pkg.Version) && !pkg.AllowsDowngrade() && semver.Compare(replace.New.Version, pkg.Version) > 0 {
+			r.Skipped = true
+			r.SkipReason = types.SkipReasonDowngrade
+			r.Message = fmt.Sprintf("requested version %q is older than current version %q", pkg.Version, replace.New.Version)
+			log.Printf("Warning: package %s: %s, skipping", pkg.Name, r.Message)
+		} else {
+			replaces[pkg.Name] = pkg
+		}
+		result.Replaces = append(result.Replaces, r)
+	}
+	return replaces
+}
@@ -0,0 +1,193 @@
+package update
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chainguard-dev/gobump/pkg/run"
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// writeWorkspace writes under a new directory a go.work using module a, requiring example.com/foo
+// v1.2.3, and module b, requiring example.com/bar v1.0.0 which go.work replaces. It returns the
+// path of go.work and a runner using it with the proxy.
+func writeWorkspace(t *testing.T) (string, *run.ExecRunner) {
+	t.Helper()
+	proxy := tempProxy(t)
+	writeProxy(t, proxy, "example.com/foo", "v1.2.3", "v1.2.5")
+	writeProxy(t, proxy, "example.com/bar", "v1.0.0", "v1.1.0")
+
+	root := t.TempDir()
+	writeModule(t, filepath.Join(root, "a"), "example.com/a", "example.com/foo v1.2.3")
+	writeModule(t, filepath.Join(root, "b"), "example.com/b", "example.com/bar v1.0.0")
+	gowork := "go 1.22\n\nuse (\n\t./a\n\t./b\n)\n\nreplace example.com/bar => example.com/bar v1.0.0\n"
+	workPath := filepath.Join(root, "go.work")
+	if err := os.WriteFile(workPath, []byte(gowork), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return workPath, proxyRunner(t, proxy, "GOFLAGS=", "GOWORK="+workPath)
+}
+
+func TestDoUpdateWorkspace(t *testing.T) {
+	workPath, runner := writeWorkspace(t)
+	root := filepath.Dir(workPath)
+
+	pkgVersions := map[string]*types.Package{
+		"example.com/foo": {Name: "example.com/foo", Version: "v1.2.5", Index: 0},
+		"example.com/bar": {Name: "example.com/bar", Version: "v1.1.0", Index: 1},
+	}
+	result, err := DoUpdateWorkspace(context.Background(), pkgVersions, &types.Config{
+		Modroot:   filepath.Join(root, "a"),
+		GoVersion: "1.22",
+		Runner:    runner,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Replaces) != 1 || result.Replaces[0].Before != "v1.0.0" || result.Replaces[0].After != "v1.1.0" {
+		t.Errorf("workspace replaces = %+v, want example.com/bar from v1.0.0 to v1.1.0", result.Replaces)
+	}
+	if len(result.Modules) != 2 {
+		t.Fatalf("got %d module results, want 2", len(result.Modules))
+	}
+	if got := getVersion(result.Modules[0].ModFile, "example.com/foo"); got != "v1.2.5" {
+		t.Errorf("example.com/foo in module a = %s, want v1.2.5", got)
+	}
+	if len(result.Modules[1].Packages) != 0 {
+		t.Errorf("module b was bumped with %+v, want the go.work replace only", result.Modules[1].Packages)
+	}
+	workFile, err := ParseGoWorkfile(workPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(workFile.Replace) != 1 || workFile.Replace[0].New.Version != "v1.1.0" {
+		t.Errorf("go.work replaces = %+v, want example.com/bar v1.1.0", workFile.Replace)
+	}
+}
+
+func TestDoUpdateWorkspaceQueries(t *testing.T) {
+	testCases := []struct {
+		name      string
+		pkg       types.Package
+		wantQuery string
+		want      string
+	}{
+		{name: "latest", pkg: types.Package{Version: "latest"}, wantQuery: "latest", want: "v1.1.0"},
+		{name: "caret", pkg: types.Package{Version: "^v1.0"}, wantQuery: "^v1.0", want: "v1.1.0"},
+		{name: "latest patch above", pkg: types.Package{Version: "v1.0.0", Mode: types.VersionModeLatestPatchAbove}, want: "v1.0.0"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			workPath, runner := writeWorkspace(t)
+			root := filepath.Dir(workPath)
+
+			pkg := tc.pkg
+			pkg.Name = "example.com/bar"
+			result, err := DoUpdateWorkspace(context.Background(), map[string]*types.Package{pkg.Name: &pkg}, &types.Config{
+				Modroot:   filepath.Join(root, "a"),
+				GoVersion: "1.22",
+				Runner:    runner,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Replaces) != 1 {
+				t.Fatalf("workspace replaces = %+v, want example.com/bar", result.Replaces)
+			}
+			if r := result.Replaces[0]; r.Query != tc.wantQuery || r.RequestedVersion != tc.want || r.After != tc.want {
+				t.Errorf("workspace replace = %+v, want query %q resolved to %s", r, tc.wantQuery, tc.want)
+			}
+		})
+	}
+}
+
+func TestDoUpdateWorkspaceDryRun(t *testing.T) {
+	workPath, runner := writeWorkspace(t)
+	root := filepath.Dir(workPath)
+	paths := []string{workPath, filepath.Join(root, "a", "go.mod"), filepath.Join(root, "b", "go.mod")}
+	want := map[string]string{}
+	for _, p := range paths {
+		content, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[p] = string(content)
+	}
+
+	pkgVersions := map[string]*types.Package{
+		"example.com/foo": {Name: "example.com/foo", Version: "v1.2.5", Index: 0},
+		"example.com/bar": {Name: "example.com/bar", Version: "v1.1.0", Index: 1},
+	}
+	result, err := DoUpdateWorkspace(context.Background(), pkgVersions, &types.Config{
+		Modroot:   filepath.Join(root, "a"),
+		GoVersion: "1.22",
+		Runner:    runner,
+		DryRun:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.DryRun || result.GoWork != workPath {
+		t.Errorf("workspace result = {GoWork: %s, DryRun: %v}, want the dry run of %s", result.GoWork, result.DryRun, workPath)
+	}
+	if len(result.Replaces) != 1 || result.Replaces[0].After != "v1.1.0" {
+		t.Errorf("workspace replaces = %+v, want example.com/bar planned at v1.1.0", result.Replaces)
+	}
+	if len(result.Modules) != 2 {
+		t.Fatalf("got %d module results, want 2", len(result.Modules))
+	}
+	if m := result.Modules[0]; m.Modroot != filepath.Join(root, "a") || getVersion(m.ModFile, "example.com/foo") != "v1.2.5" {
+		t.Errorf("module result of %s plans example.com/foo at %s, want module a at v1.2.5", m.Modroot, getVersion(m.ModFile, "example.com/foo"))
+	}
+	for _, p := range paths {
+		got, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want[p] {
+			t.Errorf("dry run modified %s:\n%s", p, got)
+		}
+	}
+}
+
+func TestDoUpdateWorkspaceRollback(t *testing.T) {
+	workPath, runner := writeWorkspace(t)
+	root := filepath.Dir(workPath)
+	paths := []string{workPath, filepath.Join(root, "a", "go.mod"), filepath.Join(root, "b", "go.mod")}
+	want := map[string]string{}
+	for _, p := range paths {
+		content, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[p] = string(content)
+	}
+
+	// The go.work replace is bumped before 'go get' fails for module a.
+	pkgVersions := map[string]*types.Package{
+		"example.com/foo": {Name: "example.com/foo", Version: "v1.9.9", Index: 0},
+		"example.com/bar": {Name: "example.com/bar", Version: "v1.1.0", Index: 1},
+	}
+	_, err := DoUpdateWorkspace(context.Background(), pkgVersions, &types.Config{
+		Modroot:       filepath.Join(root, "a"),
+		GoVersion:     "1.22",
+		Runner:        runner,
+		Transactional: true,
+	})
+	if err == nil {
+		t.Fatal("DoUpdateWorkspace() succeeded, want an error for example.com/foo v1.9.9")
+	}
+	for _, p := range paths {
+		got, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want[p] {
+			t.Errorf("%s was not rolled back:\n%s", p, got)
+		}
+	}
+}
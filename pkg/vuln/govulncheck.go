@@ -0,0 +1,154 @@
+package vuln
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/mod/semver"
+)
+
+// Finding is a vulnerability found by govulncheck.
+type Finding struct {
+	// OSV is the ID of the advisory.
+	OSV string `json:"osv"`
+	// FixedVersion is the version of the module fixing the advisory, if any.
+	FixedVersion string `json:"fixed_version,omitempty"`
+	// Trace goes from the vulnerable symbol, package or module up to the entry point in the scanned code.
+	Trace []Frame `json:"trace,omitempty"`
+}
+
+// Frame is an element of the trace of a finding.
+type Frame struct {
+	Module   string `json:"module"`
+	Version  string `json:"version,omitempty"`
+	Package  string `json:"package,omitempty"`
+	Function string `json:"function,omitempty"`
+}
+
+// Reachable tells whether the vulnerable symbol is called by the scanned code. Findings only
+// naming an imported package or a required module are not reachable.
+func (f *Finding) Reachable() bool {
+	return len(f.Trace) > 0 && f.Trace[0].Function != ""
+}
+
+// Report holds the findings of a govulncheck run.
+type Report struct {
+	Findings []Finding
+}
+
+// govulncheckMessage is a message of the 'govulncheck -json' stream, only the findings are used.
+type govulncheckMessage struct {
+	Finding *Finding `json:"finding,omitempty"`
+}
+
+// ParseGovulncheckFile reads the output of 'govulncheck -json' from file.
+func ParseGovulncheckFile(file string) (*Report, error) {
+	f, err := os.Open(filepath.Clean(file))
+	if err != nil {
+		return nil, fmt.Errorf("failed reading file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	report, err := ParseGovulncheck(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse govulncheck output %s: %w", file, err)
+	}
+	return report, nil
+}
+
+// ParseGovulncheck reads the stream of JSON messages written by 'govulncheck -json'.
+func ParseGovulncheck(r io.Reader) (*Report, error) {
+	report := &Report{}
+	dec := json.NewDecoder(r)
+	for {
+		var msg govulncheckMessage
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return report, nil
+			}
+			return nil, err
+		}
+		if msg.Finding != nil {
+			report.Findings = append(report.Findings, *msg.Finding)
+		}
+	}
+}
+
+// ReachableIDs returns the sorted IDs of the advisories with a reachable finding.
+func (r *Report) ReachableIDs() []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, f := range r.Findings {
+		if f.Reachable() && !seen[f.OSV] {
+			seen[f.OSV] = true
+			ids = append(ids, f.OSV)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Fixes returns, sorted by module path, the bump each module with a reachable finding needs.
+// The standard library and the toolchain can't be bumped from go.mod and are left out.
+func (r *Report) Fixes() []Fix {
+	byModule := map[string]*Fix{}
+	ids := map[string]map[string]bool{}
+	for _, f := range r.Findings {
+		if !f.Reachable() {
+			continue
+		}
+		// The first frame is the vulnerable symbol.
+		frame := f.Trace[0]
+		if frame.Module == "stdlib" || frame.Module == "toolchain" {
+			continue
+		}
+		fix, ok := byModule[frame.Module]
+		if !ok {
+			fix = &Fix{Module: frame.Module, Version: frame.Version}
+			byModule[frame.Module] = fix
+			ids[frame.Module] = map[string]bool{}
+		}
+		if ids[frame.Module][f.OSV] {
+			continue
+		}
+		ids[frame.Module][f.OSV] = true
+		fix.IDs = append(fix.IDs, f.OSV)
+		if f.FixedVersion == "" {
+			fix.Unfixed = append(fix.Unfixed, f.OSV)
+		} else if semver.Compare(f.FixedVersion, fix.FixedVersion) > 0 {
+			fix.FixedVersion = f.FixedVersion
+		}
+	}
+
+	fixes := make([]Fix, 0, len(byModule))
+	for _, fix := range byModule {
+		sort.Strings(fix.IDs)
+		sort.Strings(fix.Unfixed)
+		fixes = append(fixes, *fix)
+	}
+	sort.Slice(fixes, func(i, j int) bool {
+		return fixes[i].Module < fixes[j].Module
+	})
+	return fixes
+}
+
+// Compare tells which of the reachable advisories of before are resolved, and which remain, in after.
+func Compare(before, after *Report) (resolved, remaining []string) {
+	still := map[string]bool{}
+	for _, id := range after.ReachableIDs() {
+		still[id] = true
+	}
+	for _, id := range before.ReachableIDs() {
+		if still[id] {
+			remaining = append(remaining, id)
+		} else {
+			resolved = append(resolved, id)
+		}
+	}
+	return resolved, remaining
+}
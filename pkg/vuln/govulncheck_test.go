@@ -0,0 +1,55 @@
+package vuln
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGovulncheckFixes(t *testing.T) {
+	report, err := ParseGovulncheckFile("testdata/govulncheck-before.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Fix{{
+		Module:  "github.com/foo/nofix",
+		Version: "v1.0.0",
+		IDs:     []string{"GO-2024-0004"},
+		Unfixed: []string{"GO-2024-0004"},
+	}, {
+		Module:       "golang.org/x/net",
+		Version:      "v0.17.0",
+		FixedVersion: "v0.25.0",
+		IDs:          []string{"GO-2024-0002", "GO-2024-0003"},
+	}}
+	if diff := cmp.Diff(want, report.Fixes()); diff != "" {
+		t.Errorf("Fixes() (-want +got)\n%s", diff)
+	}
+}
+
+func TestGovulncheckCompare(t *testing.T) {
+	before, err := ParseGovulncheckFile("testdata/govulncheck-before.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := ParseGovulncheckFile("testdata/govulncheck-after.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, remaining := Compare(before, after)
+	if diff := cmp.Diff([]string{"GO-2024-0002", "GO-2024-0003"}, resolved); diff != "" {
+		t.Errorf("resolved (-want +got)\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"GO-2024-0004", "GO-2024-0008"}, remaining); diff != "" {
+		t.Errorf("remaining (-want +got)\n%s", diff)
+	}
+}
+
+func TestParseGovulncheckInvalid(t *testing.T) {
+	if _, err := ParseGovulncheck(strings.NewReader(`{"finding": {"osv": 1}}`)); err == nil {
+		t.Error("ParseGovulncheck() = nil, want an error")
+	}
+}
@@ -0,0 +1,265 @@
+// Package vuln computes the bumps that remediate known vulnerabilities of a module.
+package vuln
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+)
+
+// Entry is the subset of an OSV advisory, as exported by the Go vulnerability database, used by gobump.
+type Entry struct {
+	ID       string     `json:"id"`
+	Aliases  []string   `json:"aliases,omitempty"`
+	Summary  string     `json:"summary,omitempty"`
+	Affected []Affected `json:"affected"`
+}
+
+// Affected lists the affected version ranges of a module.
+type Affected struct {
+	Module Module  `json:"package"`
+	Ranges []Range `json:"ranges,omitempty"`
+}
+
+// Module identifies the affected module, Path is the module path for the Go ecosystem.
+type Module struct {
+	Path      string `json:"name"`
+	Ecosystem string `json:"ecosystem"`
+}
+
+// Range is a list of introduced and fixed events. Versions have no "v" prefix.
+type Range struct {
+	Type   string  `json:"type"`
+	Events []Event `json:"events"`
+}
+
+// Event introduces or fixes a vulnerability at a version.
+type Event struct {
+	Introduced string `json:"introduced,omitempty"`
+	Fixed      string `json:"fixed,omitempty"`
+}
+
+// LoadDir reads every OSV advisory under dir, such as an export of the Go vulnerability database.
+// JSON files that are not advisories, like the database index, are ignored.
+func LoadDir(dir string) ([]*Entry, error) {
+	var entries []*Entry
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		content, err := os.ReadFile(path) //nolint:gosec
+		if err != nil {
+			return err
+		}
+		var entry Entry
+		if err := json.Unmarshal(content, &entry); err != nil {
+			// The index files hold arrays or other objects, skip them.
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			return nil
+		}
+		if entry.ID != "" && len(entry.Affected) > 0 {
+			entries = append(entries, &entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the OSV advisories in %s: %w", dir, err)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+// Affects tells whether version of module is affected by e. When it is, fixed is the first
+// version fixing it, or empty if there is none.
+func (e *Entry) Affects(module, version string) (fixed string, affected bool) {
+	for _, a := range e.Affected {
+		if a.Module.Path != module || (a.Module.Ecosystem != "" && a.Module.Ecosystem != "Go") {
+			continue
+		}
+		for _, r := range a.Ranges {
+			if r.Type != "SEMVER" {
+				continue
+			}
+			if fixed, affected := r.affects(version); affected {
+				return fixed, true
+			}
+		}
+	}
+	return "", false
+}
+
+// affects walks the events of r, in order, looking for an introduced/fixed interval holding version.
+func (r Range) affects(version string) (fixed string, affected bool) {
+	introduced := ""
+	for _, ev := range r.Events {
+		switch {
+		case ev.Introduced != "":
+			introduced = canonical(ev.Introduced)
+		case ev.Fixed != "" && introduced != "":
+			fixed := canonical(ev.Fixed)
+			if semver.Compare(version, introduced) >= 0 && semver.Compare(version, fixed) < 0 {
+				return fixed, true
+			}
+			introduced = ""
+		}
+	}
+	if introduced != "" && semver.Compare(version, introduced) >= 0 {
+		return "", true
+	}
+	return "", false
+}
+
+// canonical turns an OSV version into a module version, "0" being the lowest version.
+func canonical(v string) string {
+	if v == "0" {
+		return "v0.0.0-0"
+	}
+	return "v" + strings.TrimPrefix(v, "v")
+}
+
+// Fix is the bump remediating the vulnerabilities of a module.
+type Fix struct {
+	// Module is the vulnerable module path.
+	Module string
+	// OldName is the replaced module path, when Module is the target of a replace directive.
+	OldName string
+	// Version is the version of Module in go.mod.
+	Version string
+	// FixedVersion is the lowest version fixing every fixable vulnerability, empty if there is none.
+	FixedVersion string
+	// IDs are the advisories affecting Version.
+	IDs []string
+	// Unfixed are the advisories without a fixed version.
+	Unfixed []string
+}
+
+// Fixes intersects the advisories with the requires and replaces of modFile and returns, sorted by
+// module path, the bump each vulnerable module needs.
+func Fixes(entries []*Entry, modFile *modfile.File) []Fix {
+	var fixes []Fix
+	// A replaced module isn't used at its required version.
+	seen := make(map[string]bool, len(modFile.Replace))
+	for _, replace := range modFile.Replace {
+		if replace.Old.Path != replace.New.Path {
+			seen[replace.Old.Path] = true
+		}
+	}
+	check := func(module, oldName, version string) {
+		if version == "" || seen[module] {
+			// Directory replaces have no version to bump.
+			return
+		}
+		seen[module] = true
+		if fix, ok := fixFor(entries, module, version); ok {
+			fix.OldName = oldName
+			fixes = append(fixes, fix)
+		}
+	}
+	// The replaces come first, they win over the requires.
+	for _, replace := range modFile.Replace {
+		check(replace.New.Path, replace.Old.Path, replace.New.Version)
+	}
+	for _, require := range modFile.Require {
+		check(require.Mod.Path, "", require.Mod.Version)
+	}
+	sort.Slice(fixes, func(i, j int) bool {
+		return fixes[i].Module < fixes[j].Module
+	})
+	return fixes
+}
+
+// fixFor computes the lowest version of module fixing every fixable advisory affecting version.
+// Bumping past a fix may land in a range introduced later, so it iterates until no fixable
+// advisory affects the candidate.
+func fixFor(entries []*Entry, module, version string) (Fix, bool) {
+	fix := Fix{Module: module, Version: version}
+	for _, e := range entries {
+		if _, affected := e.Affects(module, version); affected {
+			fix.IDs = append(fix.IDs, e.ID)
+		}
+	}
+	if len(fix.IDs) == 0 {
+		return fix, false
+	}
+
+	unfixed := map[string]bool{}
+	candidate := version
+	for {
+		moved := false
+		for _, e := range entries {
+			fixed, affected := e.Affects(module, candidate)
+			if !affected {
+				continue
+			}
+			if fixed == "" {
+				unfixed[e.ID] = true
+				continue
+			}
+			candidate, moved = fixed, true
+		}
+		if !moved {
+			break
+		}
+	}
+	if candidate != version {
+		fix.FixedVersion = candidate
+	}
+	for id := range unfixed {
+		fix.Unfixed = append(fix.Unfixed, id)
+	}
+	sort.Strings(fix.Unfixed)
+	return fix, true
+}
+
+// Packages turns the fixes into the packages to bump, skipping the fixes without a fixed version.
+// The reason of every package lists the advisories its fixed version resolves.
+func Packages(fixes []Fix) map[string]*types.Package {
+	pkgVersions := map[string]*types.Package{}
+	for _, fix := range fixes {
+		if fix.FixedVersion == "" {
+			continue
+		}
+		pkgVersions[fix.Module] = &types.Package{
+			OldName: fix.OldName,
+			Name:    fix.Module,
+			Version: fix.FixedVersion,
+			Replace: fix.OldName != "",
+			Index:   len(pkgVersions),
+			Reason:  strings.Join(fix.fixedIDs(), ", "),
+		}
+	}
+	return pkgVersions
+}
+
+// fixedIDs returns the advisories of the fix that have a fixed version.
+func (f *Fix) fixedIDs() []string {
+	unfixed := make(map[string]bool, len(f.Unfixed))
+	for _, id := range f.Unfixed {
+		unfixed[id] = true
+	}
+	var ids []string
+	for _, id := range f.IDs {
+		if !unfixed[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,77 @@
+package vuln
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/chainguard-dev/gobump/pkg/types"
+	"github.com/chainguard-dev/gobump/pkg/update"
+)
+
+func TestLoadDir(t *testing.T) {
+	entries, err := LoadDir("testdata/osv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	want := []string{"GO-2024-0001", "GO-2024-0002", "GO-2024-0003", "GO-2024-0004", "GO-2024-0005", "GO-2024-0006"}
+	if diff := cmp.Diff(want, ids); diff != "" {
+		t.Errorf("LoadDir() ids (-want +got)\n%s", diff)
+	}
+}
+
+func TestFixes(t *testing.T) {
+	entries, err := LoadDir("testdata/osv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	modFile, _, err := update.ParseGoModfile("testdata/go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixes := Fixes(entries, modFile)
+	want := []Fix{{
+		Module:       "example.com/new",
+		OldName:      "example.com/old",
+		Version:      "v1.1.0",
+		FixedVersion: "v1.2.0",
+		IDs:          []string{"GO-2024-0005"},
+	}, {
+		Module:  "github.com/foo/nofix",
+		Version: "v1.0.0",
+		IDs:     []string{"GO-2024-0004"},
+		Unfixed: []string{"GO-2024-0004"},
+	}, {
+		Module:       "golang.org/x/net",
+		Version:      "v0.17.0",
+		FixedVersion: "v0.25.0",
+		IDs:          []string{"GO-2024-0002"},
+	}}
+	if diff := cmp.Diff(want, fixes); diff != "" {
+		t.Errorf("Fixes() (-want +got)\n%s", diff)
+	}
+
+	wantPkgs := map[string]*types.Package{
+		"example.com/new": {
+			OldName: "example.com/old",
+			Name:    "example.com/new",
+			Version: "v1.2.0",
+			Replace: true,
+			Reason:  "GO-2024-0005",
+		},
+		"golang.org/x/net": {
+			Name:    "golang.org/x/net",
+			Version: "v0.25.0",
+			Index:   1,
+			Reason:  "GO-2024-0002",
+		},
+	}
+	if diff := cmp.Diff(wantPkgs, Packages(fixes)); diff != "" {
+		t.Errorf("Packages() (-want +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,12 @@
+module example.com/app
+
+go 1.22
+
+require (
+	example.com/old v1.0.0
+	github.com/foo/nofix v1.0.0
+	github.com/google/uuid v1.3.1
+	golang.org/x/net v0.17.0
+)
+
+replace example.com/old => example.com/new v1.1.0